@@ -37,7 +37,12 @@ func (h *handlers) verifyEmail(c *gin.Context) {
 		return
 	}
 
-	status := h.authHandler.VerifyEmail(c, reqData.UserID, reqData.OTP)
+	resp, status := h.authHandler.VerifyEmail(c, reqData.UserID, reqData.OTP)
+	if status == http.StatusTooManyRequests {
+		c.Header("Retry-After", strconv.FormatInt(resp.RetryAfter, 10))
+		c.JSON(status, resp)
+		return
+	}
 	c.Status(status)
 }
 
@@ -67,32 +72,110 @@ func (h *handlers) loginUser(c *gin.Context) {
 		})
 		return
 	}
+	if status == http.StatusTooManyRequests {
+		c.Header("Retry-After", strconv.FormatInt(resp.RetryAfter, 10))
+	}
 	c.JSON(status, resp)
 }
 
 func (h *handlers) renewToken(c *gin.Context) {
 	logger := pkg.Log.WithField("handler", "renewToken")
 
-	userID, exists := c.Get(UserIDKey)
+	claims, exists := c.Get(ClaimsKey)
 	if !exists {
-		logger.Error("error on getting user_id from context")
+		logger.Error("error on getting claims from context")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": pkg.ErrInternalServerError,
 		})
 		return
 	}
-	tokenType, exists := c.Get(TokenTypeKey)
+
+	// the refresh token is always renewed, the old one can't be used anymore
+	resp, status := h.authHandler.RenewToken(c, claims.(structs.TokenClaims))
+	c.JSON(status, resp)
+}
+
+func (h *handlers) logout(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "logout")
+
+	claims, exists := c.Get(ClaimsKey)
 	if !exists {
-		logger.Error("error on getting token type from context")
+		logger.Error("error on getting claims from context")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": pkg.ErrInternalServerError,
 		})
 		return
 	}
 
-	full_refresh := c.GetHeader("full-refresh") == "true" // if this header is set to true, then the refresh token will be renewed too
+	c.Status(h.authHandler.Logout(c, claims.(structs.TokenClaims)))
+}
+
+func (h *handlers) logoutAll(c *gin.Context) {
+	c.Status(h.authHandler.LogoutAll(c, c.GetInt64(UserIDKey)))
+}
+
+func (h *handlers) forgotPassword(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "forgotPassword")
+
+	var reqData structs.RequestForgotPassword
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": pkg.ErrBadRequest.Error(),
+		})
+		return
+	}
+
+	c.Status(h.authHandler.ForgotPassword(c, reqData.Email))
+}
+
+func (h *handlers) resetPassword(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "resetPassword")
 
-	resp, status := h.authHandler.RenewToken(c, userID.(int64), tokenType.(string), full_refresh)
+	var reqData structs.RequestResetPassword
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": pkg.ErrBadRequest.Error(),
+		})
+		return
+	}
+
+	resp, status := h.authHandler.ResetPassword(c, reqData)
+	if status == http.StatusTooManyRequests {
+		c.Header("Retry-After", strconv.FormatInt(resp.RetryAfter, 10))
+		c.JSON(status, resp)
+		return
+	}
+	c.Status(status)
+}
+
+// oidcLogin redirects the user to the login page of the provider
+func (h *handlers) oidcLogin(c *gin.Context) {
+	loginURL, status := h.authHandler.OIDCLoginURL(c, c.Param("provider"))
+	if status != http.StatusFound {
+		c.Status(status)
+		return
+	}
+	c.Redirect(status, loginURL)
+}
+
+// oidcCallback is where the provider redirects the user back to after logging in
+func (h *handlers) oidcCallback(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "oidcCallback")
+
+	if errCode := c.Query("error"); errCode != "" {
+		logger.Warningf("provider %v gave error %v: %v", c.Param("provider"), errCode, c.Query("error_description"))
+		c.JSON(http.StatusForbidden, structs.AuthenticateResponse{
+			Message: "login is canceled or rejected by the provider",
+		})
+		return
+	}
+
+	resp, status := h.authHandler.LoginWithOIDC(c, c.Param("provider"), c.Query("code"), c.Query("state"))
+	if status == http.StatusTooManyRequests {
+		c.Header("Retry-After", strconv.FormatInt(resp.RetryAfter, 10))
+	}
 	c.JSON(status, resp)
 }
 
@@ -169,3 +252,27 @@ func (h *handlers) getUser(c *gin.Context) {
 	resp, status := h.authHandler.GetUser(c, userID, false)
 	c.JSON(status, resp)
 }
+
+func (h *handlers) setUserRole(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "setUserRole")
+
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Error("error on getting user_id from url: ", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid user_id, user_id should be an integer",
+		})
+		return
+	}
+
+	var reqData structs.RequestSetRole
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	c.Status(h.authHandler.SetRole(c, userID, reqData.Role))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/sirupsen/logrus"
@@ -69,7 +70,18 @@ func (h *handlers) ListContests(c *gin.Context) {
 	reqData.UserID = userID.(int64)
 
 	reqData.Descending = c.Query("descending") == "true"
-	reqData.Started = c.Query("started") == "true"
+	reqData.Status = c.Query("status")
+	if reqData.Status == "" && c.Query("started") == "true" {
+		// started is kept for the old clients
+		reqData.Status = structs.ContestStatusStarted
+	}
+	if reqData.Status != "" && !slices.Contains(structs.ContestStatuses, reqData.Status) {
+		logger.Warning("invalid contest status: ", reqData.Status)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid status, status should be one of upcoming, running, finished and started",
+		})
+		return
+	}
 
 	limitStr := c.Query("limit")
 	offsetStr := c.Query("offset")
@@ -153,28 +165,6 @@ func (h *handlers) AddProblemContest(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get(UserIDKey)
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": pkg.ErrInternalServerError.Error(),
-		})
-		return
-	}
-
-	isOwner, err := h.contestsHandler.IsContestOwner(c, contestID, userID.(int64))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": pkg.ErrInternalServerError.Error(),
-		})
-		return
-	}
-	if !isOwner {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"message": "user is not authorized to modify the contest",
-		})
-		return
-	}
-
 	problemID, err := strconv.ParseInt(c.Param("problem_id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -196,28 +186,6 @@ func (h *handlers) RemoveProblemContest(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get(UserIDKey)
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": pkg.ErrInternalServerError.Error(),
-		})
-		return
-	}
-
-	isOwner, err := h.contestsHandler.IsContestOwner(c, contestID, userID.(int64))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": pkg.ErrInternalServerError.Error(),
-		})
-		return
-	}
-	if !isOwner {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"message": "user is not authorized to modify the contest",
-		})
-		return
-	}
-
 	problemID, err := strconv.ParseInt(c.Param("problem_id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -240,10 +208,21 @@ func (h *handlers) GetContestScoreboard(c *gin.Context) {
 		return
 	}
 
+	userID, exists := c.Get(UserIDKey)
+	if !exists {
+		logger.Error("error on getting user_id from context")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": pkg.ErrInternalServerError.Error(),
+		})
+		return
+	}
+
 	var reqData structs.RequestGetScoreboard
 	reqData.ContestID = contestID
+	reqData.UserID = userID.(int64)
 
 	reqData.GetCount = c.Query("get_count") == "true"
+	reqData.Virtual = c.Query("virtual") == "true"
 
 	limitStr := c.Query("limit")
 	offsetStr := c.Query("offset")
@@ -299,9 +278,87 @@ func (h *handlers) PatchContest(c *gin.Context) {
 		c.Status(h.contestsHandler.RegisterUser(c, contestID, userID.(int64)))
 	case "unregister":
 		c.Status(h.contestsHandler.UnregisterUser(c, contestID, userID.(int64)))
+	case "virtual":
+		c.Status(h.contestsHandler.RegisterVirtual(c, contestID, userID.(int64)))
+	case "reveal":
+		if err := h.authorizer.CanEditContest(c, userID.(int64), contestID); err != nil {
+			abortUnauthorized(c, logger, err)
+			return
+		}
+		resp, status := h.contestsHandler.RevealSubmission(c, contestID)
+		if status != http.StatusOK {
+			c.Status(status)
+			return
+		}
+		c.JSON(status, resp)
+	case "unfreeze":
+		if err := h.authorizer.CanEditContest(c, userID.(int64), contestID); err != nil {
+			abortUnauthorized(c, logger, err)
+			return
+		}
+		c.Status(h.contestsHandler.Unfreeze(c, contestID))
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "action " + action + " not defined",
 		})
 	}
 }
+
+func (h *handlers) ListContestStaff(c *gin.Context) {
+	contestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid contest id, id should be an integer",
+		})
+		return
+	}
+
+	resp, status := h.contestsHandler.ListStaff(c, contestID)
+	if status != http.StatusOK {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, resp)
+}
+
+func (h *handlers) AddContestStaff(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "AddContestStaff")
+
+	contestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid contest id, id should be an integer",
+		})
+		return
+	}
+
+	var reqData structs.RequestAddContestStaff
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	c.Status(h.contestsHandler.AddStaff(c, contestID, reqData))
+}
+
+func (h *handlers) RemoveContestStaff(c *gin.Context) {
+	contestID, err := strconv.ParseInt(c.Param("contest_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid contest id, id should be an integer",
+		})
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid user id, id should be an integer",
+		})
+		return
+	}
+
+	c.Status(h.contestsHandler.RemoveStaff(c, contestID, userID))
+}
@@ -3,10 +3,14 @@ package api
 import (
 	"net/http"
 
+	"github.com/ocontest/backend/internal/authz"
 	"github.com/ocontest/backend/internal/oc/auth"
 	"github.com/ocontest/backend/internal/oc/contests"
 	"github.com/ocontest/backend/internal/oc/problems"
 	"github.com/ocontest/backend/internal/oc/submissions"
+	"github.com/ocontest/backend/internal/ratelimit"
+
+	"github.com/ocontest/backend/pkg/structs"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,15 +20,19 @@ type handlers struct {
 	problemsHandler    problems.ProblemsHandler
 	contestsHandler    contests.ContestsHandler
 	submissionsHandler submissions.Handler
+	authorizer         authz.Authorizer
+	ipLimiter          ratelimit.Limiter // limits the requests of every ip to the auth endpoints
 }
 
 func AddRoutes(r *gin.Engine, authHandler auth.AuthHandler, problemHandler problems.ProblemsHandler, submissionsHandler submissions.Handler,
-	contestsHandler contests.ContestsHandler) {
+	contestsHandler contests.ContestsHandler, authorizer authz.Authorizer, ipLimiter ratelimit.Limiter) {
 	h := handlers{
 		authHandler:        authHandler,
 		problemsHandler:    problemHandler,
 		submissionsHandler: submissionsHandler,
 		contestsHandler:    contestsHandler,
+		authorizer:         authorizer,
+		ipLimiter:          ipLimiter,
 	}
 
 	r.Use(h.corsHandler)
@@ -42,39 +50,59 @@ func AddRoutes(r *gin.Engine, authHandler auth.AuthHandler, problemHandler probl
 	{
 		authGroup := v1.Group("/auth")
 		{
-			authGroup.POST("/register", h.registerUser)
-			authGroup.POST("/verify", h.verifyEmail)
-			authGroup.POST("/otp", h.GetOTPForLogin)
-			authGroup.POST("/login", h.loginUser)
+			authGroup.POST("/register", h.RateLimit(), h.registerUser)
+			authGroup.POST("/verify", h.RateLimit(), h.verifyEmail)
+			authGroup.POST("/otp", h.RateLimit(), h.GetOTPForLogin)
+			authGroup.POST("/login", h.RateLimit(), h.loginUser)
+			authGroup.POST("/forgot_password", h.RateLimit(), h.forgotPassword)
+			authGroup.POST("/reset_password", h.RateLimit(), h.resetPassword)
+			authGroup.GET("/oidc/:provider", h.RateLimit(), h.oidcLogin)
+			authGroup.GET("/oidc/:provider/callback", h.RateLimit(), h.oidcCallback)
 			authGroup.POST("/renew_token", h.AuthMiddleware(), h.renewToken)
+			authGroup.POST("/logout", h.AuthMiddleware(), h.logout)
+			authGroup.POST("/logout_all", h.AuthMiddleware(), h.logoutAll)
 			authGroup.POST("/edit_user", h.AuthMiddleware(), h.editUser)
 			authGroup.GET("", h.AuthMiddleware(), h.getOwnUser)
 			authGroup.GET("/:id", h.AuthMiddleware(), h.getUser)
+			authGroup.PUT("/:id/role", h.AuthMiddleware(), h.RequireRole(structs.RoleAdmin), h.setUserRole)
 		}
 		problemGroup := v1.Group("/problems", h.AuthMiddleware())
 		{
-			problemGroup.POST("", h.CreateProblem)
-			problemGroup.GET("/:id", h.GetProblem)
+			canEditProblem := h.Authorize("id", h.authorizer.CanEditProblem)
+			canViewProblem := h.Authorize("id", h.authorizer.CanViewProblem)
+			problemGroup.POST("", h.RequireRole(structs.RoleSetter), h.CreateProblem)
+			problemGroup.GET("/:id", canViewProblem, h.GetProblem)
 			problemGroup.GET("", h.ListProblems)
-			problemGroup.PUT("/:id", h.UpdateProblem)
-			problemGroup.DELETE("/:id", h.DeleteProblem)
-			problemGroup.POST("/:id/testcase", h.AddTestCase)
-			problemGroup.GET("/:id/testcase", h.GetTestCase)
-			problemGroup.GET("/:id/submissions", h.ListSubmissions)
+			problemGroup.PUT("/:id", canEditProblem, h.UpdateProblem)
+			problemGroup.PUT("/:id/checker", canEditProblem, h.SetChecker)
+			problemGroup.DELETE("/:id", canEditProblem, h.DeleteProblem)
+			problemGroup.POST("/:id/testcase", canEditProblem, h.AddTestCase)
+			problemGroup.GET("/:id/testcase", canEditProblem, h.GetTestCase)
+			problemGroup.GET("/:id/submissions", canViewProblem, h.ListSubmissions)
+			problemGroup.POST("/:id/rejudge", canEditProblem, h.RejudgeProblem)
+			problemGroup.GET("/:id/access", canEditProblem, h.ListProblemAccess)
+			problemGroup.PUT("/:id/access", canEditProblem, h.GrantProblemAccess)
+			problemGroup.DELETE("/:id/access/:user_id", canEditProblem, h.RevokeProblemAccess)
 		}
 		contestGroup := v1.Group("/contests", h.AuthMiddleware())
 		{
-			contestGroup.POST("", h.CreateContest)
+			canEditContest := h.Authorize("contest_id", h.authorizer.CanEditContest)
+			canManageContest := h.Authorize("contest_id", h.authorizer.CanManageContest)
+			contestGroup.POST("", h.RequireRole(structs.RoleSetter), h.CreateContest)
 			contestGroup.GET("", h.ListContests)
 			contestGroup.GET("/:id", h.GetContest)
 			contestGroup.GET("/:id/scoreboard", h.GetContestScoreboard)
-			contestGroup.PUT("/:id", h.UpdateContest)
-			contestGroup.DELETE("/:contest_id", h.DeleteContest)
-			contestGroup.POST("/:contest_id/problems/:problem_id", h.AddProblemContest)
-			contestGroup.DELETE("/:contest_id/problems/:problem_id", h.RemoveProblemContest)
+			contestGroup.PUT("/:id", h.Authorize("id", h.authorizer.CanEditContest), h.UpdateContest)
+			contestGroup.DELETE("/:contest_id", canManageContest, h.DeleteContest)
+			contestGroup.POST("/:contest_id/problems/:problem_id", canEditContest, h.Authorize("problem_id", h.authorizer.CanEditProblem), h.AddProblemContest)
+			contestGroup.DELETE("/:contest_id/problems/:problem_id", canEditContest, h.RemoveProblemContest)
 			contestGroup.PATCH("/:contest_id", h.PatchContest)
 			contestGroup.GET("/:id/submissions", h.ListContestSubmissions)
 			contestGroup.GET("/:id/problems/:problem_id/submissions", h.ListContestProblemSubmissions)
+			contestGroup.POST("/:contest_id/rejudge", canEditContest, h.RejudgeContest)
+			contestGroup.GET("/:id/staff", h.Authorize("id", h.authorizer.CanEditContest), h.ListContestStaff)
+			contestGroup.PUT("/:id/staff", h.Authorize("id", h.authorizer.CanManageContest), h.AddContestStaff)
+			contestGroup.DELETE("/:contest_id/staff/:user_id", canManageContest, h.RemoveContestStaff)
 		}
 
 		submissionGroup := v1.Group("/submissions", h.AuthMiddleware())
@@ -82,7 +110,10 @@ func AddRoutes(r *gin.Engine, authHandler auth.AuthHandler, problemHandler probl
 			submissionGroup.GET("/:id", h.GetSubmission)
 			submissionGroup.POST("/", h.Submit)
 			submissionGroup.GET("/:id/results", h.GetSubmissionResult)
+			submissionGroup.POST("/:id/rejudge", h.Authorize("id", h.authorizer.CanEditSubmission), h.RejudgeSubmission)
 		}
+		v1.GET("/submissions/:id/events", h.StreamAuthMiddleware(), h.StreamSubmissionResults)
+		v1.GET("/rejudges/:id", h.AuthMiddleware(), h.GetRejudge)
 		v1.POST("/problems/:id/submit", h.AuthMiddleware(), h.Submit)
 	}
 }
@@ -1,17 +1,33 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ocontest/backend/internal/ratelimit"
 	"github.com/ocontest/backend/pkg"
 	"github.com/sirupsen/logrus"
 )
 
 const UserIDKey = "user_id"
 const TokenTypeKey = "token_type"
+const ClaimsKey = "claims"
 
 func (h *handlers) AuthMiddleware() gin.HandlerFunc {
+	return h.authenticate(false)
+}
+
+// StreamAuthMiddleware is AuthMiddleware which also takes the token from the token query parameter, since
+// EventSource of browsers can't set headers. tokens in urls end up in logs, so it's only for event streams
+func (h *handlers) StreamAuthMiddleware() gin.HandlerFunc {
+	return h.authenticate(true)
+}
+
+func (h *handlers) authenticate(allowQueryToken bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger := pkg.Log.WithField("middleware", "Auth")
 
@@ -19,27 +35,108 @@ func (h *handlers) AuthMiddleware() gin.HandlerFunc {
 		if authHeader == "" {
 			authHeader = c.GetHeader("X-Authorization")
 		}
+		if authHeader == "" && allowQueryToken {
+			authHeader = c.Query("token")
+		}
 
 		authHeader = strings.Replace(authHeader, "Bearer ", "", 1)
-		userId, typ, err := h.authHandler.ParseAuthToken(c, authHeader)
+		claims, err := h.authHandler.ParseAuthToken(c, authHeader)
 
 		if err != nil {
 			logger.WithFields(logrus.Fields{
-				"error":  err.Error(),
-				"header": authHeader,
+				"error": err.Error(),
+				"path":  c.FullPath(),
 			}).Error("error on parsing token")
 
 			c.AbortWithStatusJSON(401, gin.H{"message": "invalid token"})
 			return
 		}
 
-		c.Set(UserIDKey, userId)
-		c.Set(TokenTypeKey, typ)
+		c.Set(UserIDKey, claims.UserID)
+		c.Set(TokenTypeKey, claims.Type)
+		c.Set(ClaimsKey, claims)
+
+		c.Next()
+	}
+}
+
+// RateLimit limits the requests of every ip, it answers 429 with Retry-After to the requests over the limit.
+// errors of the limiter don't block the requests
+func (h *handlers) RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := pkg.Log.WithField("middleware", "RateLimit")
+
+		wait, err := h.ipLimiter.Hit(c, c.ClientIP())
+		if err != nil {
+			logger.Error("error on counting request: ", err)
+		}
+		if wait > 0 {
+			logger.Warningf("too many requests from %v to %v", c.ClientIP(), c.FullPath())
+			c.Header("Retry-After", strconv.FormatInt(ratelimit.Seconds(wait), 10))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"message": "too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole lets through only the users who have one of the roles, admins are always let through.
+// it should come after AuthMiddleware
+func (h *handlers) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := pkg.Log.WithField("middleware", "RequireRole")
+
+		err := h.authorizer.HasRole(c, c.GetInt64(UserIDKey), roles...)
+		if err != nil {
+			abortUnauthorized(c, logger, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Authorize lets through only the users who pass check for the id in param of the path,
+// checks are the methods of authz.Authorizer. it should come after AuthMiddleware
+func (h *handlers) Authorize(param string, check func(ctx context.Context, userID, id int64) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := pkg.Log.WithField("middleware", "Authorize")
+
+		id, err := strconv.ParseInt(c.Param(param), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "invalid " + param + ", it should be an integer",
+			})
+			return
+		}
 
+		err = check(c, c.GetInt64(UserIDKey), id)
+		if err != nil {
+			abortUnauthorized(c, logger, err)
+			return
+		}
 		c.Next()
 	}
 }
 
+func abortUnauthorized(c *gin.Context, logger *logrus.Entry, err error) {
+	switch {
+	case errors.Is(err, pkg.ErrForbidden):
+		logger.Warningf("forbidden %v %v for user %v", c.Request.Method, c.FullPath(), c.GetInt64(UserIDKey))
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": pkg.ErrForbidden.Error(),
+		})
+	case errors.Is(err, pkg.ErrNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": pkg.ErrNotFound.Error(),
+		})
+	default:
+		logger.Error("error on authorization: ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": pkg.ErrInternalServerError.Error(),
+		})
+	}
+}
+
 func (h *handlers) corsHandler(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
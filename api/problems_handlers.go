@@ -23,7 +23,7 @@ func (h *handlers) CreateProblem(c *gin.Context) {
 		return
 	}
 
-	reqData.IsPrivate = false
+	// problems of contests are kept private until they are made public by their owner
 	if reqData.ContestID != 0 {
 		reqData.IsPrivate = true
 	}
@@ -117,6 +117,32 @@ func (h *handlers) UpdateProblem(c *gin.Context) {
 	c.Status(status)
 }
 
+func (h *handlers) SetChecker(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "setChecker")
+
+	var reqData structs.RequestSetChecker
+	var err error
+	reqData.ProblemID, err = strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Warn("Failed to parse id", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": pkg.ErrBadRequest.Error(),
+		})
+		return
+	}
+
+	status := h.problemsHandler.SetChecker(c, reqData)
+	c.Status(status)
+}
+
 func (h *handlers) DeleteProblem(c *gin.Context) {
 	logger := pkg.Log.WithField("handler", "deleteProblem")
 
@@ -180,3 +206,62 @@ func (h *handlers) GetTestCase(c *gin.Context) {
 	ans, status := h.problemsHandler.GetTestcase(c, problemID)
 	c.JSON(status, ans)
 }
+
+func (h *handlers) ListProblemAccess(c *gin.Context) {
+	problemID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	resp, status := h.problemsHandler.ListAccess(c, problemID)
+	if status != http.StatusOK {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, resp)
+}
+
+func (h *handlers) GrantProblemAccess(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "GrantProblemAccess")
+
+	problemID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	var reqData structs.RequestGrantProblemAccess
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		logger.Warn("Failed to read request body", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	c.Status(h.problemsHandler.GrantAccess(c, problemID, reqData))
+}
+
+func (h *handlers) RevokeProblemAccess(c *gin.Context) {
+	problemID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid user id, id should be an integer",
+		})
+		return
+	}
+
+	c.Status(h.problemsHandler.RevokeAccess(c, problemID, userID))
+}
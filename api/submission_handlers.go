@@ -1,6 +1,7 @@
 package api
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 
@@ -45,6 +46,11 @@ func (h *handlers) Submit(c *gin.Context) {
 		return
 	}
 
+	language := c.Query("language")
+	if language == "" {
+		language = "python"
+	}
+
 	var contestID int64
 	contestIDStr := c.Query("contest_id")
 	if contestIDStr == "" {
@@ -66,7 +72,7 @@ func (h *handlers) Submit(c *gin.Context) {
 		Code:        buffer,
 		FileName:    file_name,
 		ContentType: c.GetHeader("Content-Type"),
-		Language:    "python", // For now just python
+		Language:    language,
 	}
 
 	submissionID, status := h.submissionsHandler.Submit(c, reqData)
@@ -132,6 +138,47 @@ func (h *handlers) GetSubmissionResult(c *gin.Context) {
 	c.JSON(status, resp)
 }
 
+// StreamSubmissionResults sends the progress of judging a submission as server-sent events until it is judged,
+// name of each event is the status of the submission
+func (h *handlers) StreamSubmissionResults(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "StreamSubmissionResults")
+
+	userID, exists := c.Get(UserIDKey)
+	if !exists {
+		logger.Error("error on getting user_id from context")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": pkg.ErrInternalServerError.Error(),
+		})
+		return
+	}
+
+	submissionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Error("error on getting id from request: ", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	events, status := h.submissionsHandler.Watch(c.Request.Context(), userID.(int64), submissionID)
+	if status != http.StatusOK {
+		c.Status(status)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		progress, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(progress.Status, progress)
+		return true
+	})
+}
+
 func (h *handlers) ListSubmissions(c *gin.Context) {
 	logger := pkg.Log.WithField("handler", "ListSubmissions")
 	var reqData structs.RequestListSubmissions
@@ -313,3 +360,76 @@ func (h *handlers) ListContestProblemSubmissions(c *gin.Context) {
 		c.Status(status)
 	}
 }
+
+func (h *handlers) RejudgeSubmission(c *gin.Context) {
+	h.rejudge(c, "RejudgeSubmission", "id", func(req *structs.RequestRejudge, id int64) { req.SubmissionID = id })
+}
+
+func (h *handlers) RejudgeProblem(c *gin.Context) {
+	h.rejudge(c, "RejudgeProblem", "id", func(req *structs.RequestRejudge, id int64) { req.ProblemID = id })
+}
+
+func (h *handlers) RejudgeContest(c *gin.Context) {
+	h.rejudge(c, "RejudgeContest", "contest_id", func(req *structs.RequestRejudge, id int64) { req.ContestID = id })
+}
+
+// rejudge starts a rejudge of what the param in path is, setID puts it in the request
+func (h *handlers) rejudge(c *gin.Context, name, param string, setID func(*structs.RequestRejudge, int64)) {
+	logger := pkg.Log.WithField("handler", name)
+
+	userID, exists := c.Get(UserIDKey)
+	if !exists {
+		logger.Error("error on getting user_id from context")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": pkg.ErrInternalServerError.Error(),
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
+	if err != nil {
+		logger.Error("error on getting id from request: ", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	req := structs.RequestRejudge{UserID: userID.(int64)}
+	setID(&req, id)
+	resp, status := h.submissionsHandler.Rejudge(c, req)
+	if status != http.StatusOK {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, resp)
+}
+
+func (h *handlers) GetRejudge(c *gin.Context) {
+	logger := pkg.Log.WithField("handler", "GetRejudge")
+
+	userID, exists := c.Get(UserIDKey)
+	if !exists {
+		logger.Error("error on getting user_id from context")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": pkg.ErrInternalServerError.Error(),
+		})
+		return
+	}
+
+	rejudgeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Error("error on getting id from request: ", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id, id should be an integer",
+		})
+		return
+	}
+
+	resp, status := h.submissionsHandler.GetRejudge(c, userID.(int64), rejudgeID)
+	if status != http.StatusOK {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/ocontest/backend/runner"
+	"github.com/spf13/cobra"
+)
+
+// limitExecCmd is executed by the runner to apply RLIMIT_AS before exec, it is not meant to be run by hand
+var limitExecCmd = &cobra.Command{
+	Use:                runner.LimitExecCommand,
+	Short:              "internal entry point of the runner for limiting the address space",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		runner.LimitExec(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(limitExecCmd)
+}
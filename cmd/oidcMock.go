@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ocontest/backend/internal/oidc"
+	"github.com/spf13/cobra"
+)
+
+// oidcMockCmd runs a mock openid connect provider for trying the oidc login locally, its login page accepts
+// any email without a password
+var oidcMockCmd = &cobra.Command{
+	Use:   "oidcMock <address> <client_id> [client_secret]",
+	Short: "runs a mock oidc provider, set its issuer to http://<address> in the config of a provider",
+	Args:  cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		address, clientID, clientSecret := args[0], args[1], ""
+		if len(args) == 3 {
+			clientSecret = args[2]
+		}
+
+		issuer := "http://" + address
+		provider, err := oidc.NewMockProvider(issuer, clientID, clientSecret)
+		if err != nil {
+			log.Fatal("error on creating mock provider: ", err)
+		}
+		fmt.Printf("mock oidc provider is listening, its issuer is %v\n", issuer)
+		log.Fatal(http.ListenAndServe(address, provider))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(oidcMockCmd)
+}
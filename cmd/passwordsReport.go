@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ocontest/backend/internal/db"
+	"github.com/ocontest/backend/internal/db/repos"
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/password"
+	"github.com/spf13/cobra"
+)
+
+// passwordsReportCmd lists the users whose passwords are still stored by aes, they are hashed on the next login
+var passwordsReportCmd = &cobra.Command{
+	Use:   "passwordsReport",
+	Short: "lists the users whose passwords are not migrated from aes to hash yet",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configs.InitConf()
+		c := configs.Conf
+		pkg.InitLog(c.Log)
+
+		ctx := context.Background()
+		repoWrapper, err := db.NewRepoWrapper(ctx, c.SQLDB)
+		if err != nil {
+			log.Fatal("couldn't connect to db error: ", err)
+		}
+		var usersRepo repos.UsersRepo
+		if err := repoWrapper(ctx, &usersRepo); err != nil {
+			log.Fatal("error on creating auth repos: ", err)
+		}
+
+		users, err := usersRepo.ListUsers(ctx)
+		if err != nil {
+			log.Fatal("error on listing users: ", err)
+		}
+		passwordHandler := password.NewPasswordHandler(c.Auth.PasswordCost)
+		notMigrated := 0
+		for _, user := range users {
+			if user.EncryptedPassword == "" || passwordHandler.IsHashed(user.EncryptedPassword) {
+				continue
+			}
+			notMigrated++
+			fmt.Printf("%v\t%v\t%v\n", user.ID, user.Username, user.Email)
+		}
+		fmt.Printf("%v of %v users are not migrated\n", notMigrated, len(users))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passwordsReportCmd)
+}
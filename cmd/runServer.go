@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/ocontest/backend/pkg/kvstorages"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ocontest/backend/api"
+	"github.com/ocontest/backend/internal/authz"
 	"github.com/ocontest/backend/internal/db/mongodb"
 	"github.com/ocontest/backend/internal/judge"
 	"github.com/ocontest/backend/internal/jwt"
@@ -27,10 +29,14 @@ import (
 	"github.com/ocontest/backend/internal/oc/contests"
 	"github.com/ocontest/backend/internal/oc/problems"
 	"github.com/ocontest/backend/internal/oc/submissions"
+	"github.com/ocontest/backend/internal/oidc"
 	"github.com/ocontest/backend/internal/otp"
+	"github.com/ocontest/backend/internal/ratelimit"
+	"github.com/ocontest/backend/internal/session"
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/aes"
 	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/password"
 	"github.com/ocontest/backend/pkg/smtp"
 
 	"github.com/spf13/cobra"
@@ -85,19 +91,30 @@ func getServer() (*http.Server, func() error) {
 	// connecting to dependencies
 	jwtHandler := jwt.NewGenerator(c.JWT)
 
-	smtpHandler := smtp.NewSMTPHandler(c.SMTP)
+	smtpHandler, err := smtp.NewSMTPHandler(c.SMTP)
+	if err != nil {
+		log.Fatal("error on creating smtp handler: ", err)
+	}
 
 	aesHandler, err := aes.NewAesHandler([]byte(c.AESKey))
 	if err != nil {
 		log.Fatal("error on creating aes handler: ", err)
 	}
+	passwordHandler := password.NewPasswordHandler(c.Auth.PasswordCost)
 
 	kvStore, err := kvstorages.NewKVStorage(c.KVStore)
 	if err != nil {
 		log.Fatal("coudn't initialize kvstore:  ", err)
 	}
 
-	otpHandler := otp.NewOTPHandler(kvStore)
+	otpHandler := otp.NewOTPHandler(kvStore, c.Auth.RateLimit.OTPAttempts, c.Auth.Duration.VerifyEmail)
+	sessionHandler := session.NewSessionHandler(kvStore, c.Auth.Duration.RefreshToken)
+	ipLimiter := ratelimit.NewLimiter(kvStore, "rate_limit/ip", c.Auth.RateLimit.Requests, c.Auth.RateLimit.Window)
+	loginLimiter := ratelimit.NewLimiter(kvStore, "rate_limit/login", c.Auth.RateLimit.MaxFailures, c.Auth.RateLimit.Lockout)
+	oidcHandler, err := oidc.NewHandler(c.OIDC, kvStore)
+	if err != nil {
+		log.Fatal("error on creating oidc handler: ", err)
+	}
 
 	mongoConn, err := mongodb.NewConn(ctx, c.Mongo)
 	if err != nil {
@@ -165,24 +182,34 @@ func getServer() (*http.Server, func() error) {
 	if err != nil {
 		log.Fatal("error on creating contest users repos: ", err)
 	}
+	var transactor repos.Transactor
+	err = repoWrapper(ctx, &transactor)
+	if err != nil {
+		log.Fatal("error on creating transactor: ", err)
+	}
 
 	// initiating module handlers
-	judgeHandler, err := judge.NewJudge(c.Judge, submissionsRepo, minioClient, testcaseRepo, contestsUsersRepo, judgeRepo, problemsMetadataRepo)
+	judgeHandler, err := judge.NewJudge(c.Judge, submissionsRepo, minioClient, testcaseRepo, contestsUsersRepo, judgeRepo, problemsMetadataRepo, contestRepo, transactor)
 	if err != nil {
 		log.Fatal("error on creating judge handler", err)
 	}
-	authHandler := auth.NewAuthHandler(authRepo, jwtHandler, smtpHandler, c, aesHandler, otpHandler)
-	problemsHandler := problems.NewProblemsHandler(problemsMetadataRepo, problemsDescriptionRepo, testcaseRepo)
+	go judgeHandler.StartResultProcessor()
+	authorizer := authz.NewAuthorizer(authRepo, problemsMetadataRepo, contestRepo, contestsUsersRepo, contestsProblemsRepo, submissionsRepo)
+	authHandler := auth.NewAuthHandler(authRepo, jwtHandler, smtpHandler, c, aesHandler, passwordHandler, otpHandler, sessionHandler, loginLimiter, oidcHandler)
+	problemsHandler := problems.NewProblemsHandler(problemsMetadataRepo, problemsDescriptionRepo, testcaseRepo, authRepo)
 	submissionsHandler := submissions.NewSubmissionsHandler(
 		submissionsRepo,
-		contestRepo, contestsProblemsRepo, contestsUsersRepo, minioClient, judgeHandler)
+		contestRepo, contestsProblemsRepo, contestsUsersRepo, problemsMetadataRepo, minioClient, judgeHandler, authorizer)
 	contestHandler := contests.NewContestsHandler(
 		contestRepo, contestsProblemsRepo, problemsMetadataRepo,
-		submissionsRepo, authRepo, contestsUsersRepo, judgeHandler)
+		submissionsRepo, authRepo, contestsUsersRepo, judgeHandler, authorizer)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies(c.Server.TrustedProxies)); err != nil {
+		log.Fatal("invalid trusted proxies: ", err)
+	}
 	// starting http server
-	api.AddRoutes(r, authHandler, problemsHandler, submissionsHandler, contestHandler)
+	api.AddRoutes(r, authHandler, problemsHandler, submissionsHandler, contestHandler, authorizer, ipLimiter)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port),
@@ -207,6 +234,11 @@ func getServer() (*http.Server, func() error) {
 				pkg.Log.WithError(err).Info("kv store closed")
 				return err
 			},
+			func() error {
+				err := smtpHandler.Close(ctx)
+				pkg.Log.WithError(err).Info("smtp queue closed")
+				return err
+			},
 			func() error {
 				err := mongoConn.Disconnect(ctx)
 				pkg.Log.WithError(err).Info("mongo conn closed")
@@ -225,3 +257,14 @@ func getServer() (*http.Server, func() error) {
 
 	return srv, shutdown
 }
+
+// trustedProxies splits the comma separated proxies of the config, nil means no proxy is trusted
+func trustedProxies(proxies string) []string {
+	var ans []string
+	for _, p := range strings.Split(proxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			ans = append(ans, p)
+		}
+	}
+	return ans
+}
@@ -46,7 +46,7 @@ func init() {
 }
 
 func RunRunnerTaskHandler(c *configs.OContestConf) {
-	runnerHandler, err := runner.NewRunnerScheduler(c.Judge.Nats)
+	runnerHandler, err := runner.NewRunnerScheduler(c.Judge)
 	if err != nil {
 		log.Fatal("error on creating runner scheduler: ", err)
 	}
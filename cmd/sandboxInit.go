@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/ocontest/backend/runner"
+	"github.com/spf13/cobra"
+)
+
+// sandboxInitCmd is executed by the isolated runner inside new namespaces, it is not meant to be run by hand
+var sandboxInitCmd = &cobra.Command{
+	Use:                runner.SandboxInitCommand,
+	Short:              "internal entry point of the isolated runner",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		runner.SandboxInit(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sandboxInitCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/ocontest/backend/internal/db"
+	"github.com/ocontest/backend/internal/db/repos"
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/spf13/cobra"
+)
+
+// setRoleCmd changes the role of a user, the first admin can only be made by it
+var setRoleCmd = &cobra.Command{
+	Use:   "setRole <username> <role>",
+	Short: "changes the role of a user, the role is one of admin, setter and contestant",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		configs.InitConf()
+		c := configs.Conf
+		pkg.InitLog(c.Log)
+
+		username, role := args[0], args[1]
+		if !slices.Contains(structs.Roles, role) {
+			log.Fatal("unknown role: ", role)
+		}
+
+		ctx := context.Background()
+		repoWrapper, err := db.NewRepoWrapper(ctx, c.SQLDB)
+		if err != nil {
+			log.Fatal("couldn't connect to db error: ", err)
+		}
+		var usersRepo repos.UsersRepo
+		if err := repoWrapper(ctx, &usersRepo); err != nil {
+			log.Fatal("error on creating auth repos: ", err)
+		}
+
+		user, err := usersRepo.GetByUsername(ctx, username)
+		if err != nil {
+			log.Fatal("error on getting user: ", err)
+		}
+		if err := usersRepo.SetRole(ctx, user.ID, role); err != nil {
+			log.Fatal("error on setting role: ", err)
+		}
+		fmt.Printf("role of %v is changed from %v to %v\n", username, user.Role, role)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRoleCmd)
+}
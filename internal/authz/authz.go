@@ -0,0 +1,173 @@
+package authz
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ocontest/backend/internal/db/repos"
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+)
+
+// Authorizer decides who can change problems and contests. every check gives nil if the user is allowed,
+// pkg.ErrForbidden if the user is not, and pkg.ErrNotFound if there is no such problem, contest or submission.
+// admins pass all of the checks
+type Authorizer interface {
+	HasRole(ctx context.Context, userID int64, roles ...string) error
+	CanViewProblem(ctx context.Context, userID, problemID int64) error
+	CanEditProblem(ctx context.Context, userID, problemID int64) error
+	CanEditContest(ctx context.Context, userID, contestID int64) error
+	CanManageContest(ctx context.Context, userID, contestID int64) error
+	CanTestContest(ctx context.Context, userID, contestID int64) error
+	CanEditSubmission(ctx context.Context, userID, submissionID int64) error
+}
+
+type AuthorizerImp struct {
+	usersRepo            repos.UsersRepo
+	problemsRepo         repos.ProblemsMetadataRepo
+	contestsRepo         repos.ContestsMetadataRepo
+	contestsUsersRepo    repos.ContestsUsersRepo
+	contestsProblemsRepo repos.ContestsProblemsRepo
+	submissionsRepo      repos.SubmissionMetadataRepo
+}
+
+func NewAuthorizer(
+	usersRepo repos.UsersRepo, problemsRepo repos.ProblemsMetadataRepo,
+	contestsRepo repos.ContestsMetadataRepo, contestsUsersRepo repos.ContestsUsersRepo,
+	contestsProblemsRepo repos.ContestsProblemsRepo, submissionsRepo repos.SubmissionMetadataRepo) Authorizer {
+	return &AuthorizerImp{
+		usersRepo:            usersRepo,
+		problemsRepo:         problemsRepo,
+		contestsRepo:         contestsRepo,
+		contestsUsersRepo:    contestsUsersRepo,
+		contestsProblemsRepo: contestsProblemsRepo,
+		submissionsRepo:      submissionsRepo,
+	}
+}
+
+// HasRole checks that the user has one of the roles
+func (a *AuthorizerImp) HasRole(ctx context.Context, userID int64, roles ...string) error {
+	user, err := a.usersRepo.GetByID(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get user")
+	}
+	if user.Role == structs.RoleAdmin || slices.Contains(roles, user.Role) {
+		return nil
+	}
+	return pkg.ErrForbidden
+}
+
+// CanViewProblem checks that the user can see the problem. public problems are seen by everyone, private ones by
+// the users who can edit them, the users they are shared with, and the users who can see them in a contest.
+// that is the staff of the contest, or its participants after the start
+func (a *AuthorizerImp) CanViewProblem(ctx context.Context, userID, problemID int64) error {
+	problem, err := a.problemsRepo.GetProblem(ctx, problemID)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't get problem")
+	}
+	if !problem.IsPrivate || problem.CreatedBy == userID {
+		return nil
+	}
+
+	shared, err := a.problemsRepo.HasAccess(ctx, problemID, userID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't check access to problem")
+	}
+	if shared {
+		return nil
+	}
+
+	contestIDs, err := a.contestsProblemsRepo.GetProblemContests(ctx, problemID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get contests of problem")
+	}
+	for _, contestID := range contestIDs {
+		err := a.CanTestContest(ctx, userID, contestID)
+		if !errors.Is(err, pkg.ErrForbidden) {
+			return err
+		}
+
+		registered, err := a.contestsUsersRepo.IsRegistered(ctx, contestID, userID)
+		if err != nil {
+			return errors.Wrap(err, "couldn't check registration in contest")
+		}
+		if !registered {
+			continue
+		}
+		started, err := a.contestsRepo.HasStarted(ctx, contestID)
+		if err != nil {
+			return errors.Wrap(err, "couldn't check start of contest")
+		}
+		if started {
+			return nil
+		}
+	}
+	// admins are checked by CanTestContest too, but the problem may not be in any contest
+	return a.HasRole(ctx, userID, structs.RoleAdmin)
+}
+
+// CanEditProblem checks that the user is the owner of the problem
+func (a *AuthorizerImp) CanEditProblem(ctx context.Context, userID, problemID int64) error {
+	problem, err := a.problemsRepo.GetProblem(ctx, problemID)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't get problem")
+	}
+	if problem.CreatedBy == userID {
+		return nil
+	}
+	return a.HasRole(ctx, userID, structs.RoleAdmin)
+}
+
+// CanEditContest checks that the user is the owner or a co-owner of the contest
+func (a *AuthorizerImp) CanEditContest(ctx context.Context, userID, contestID int64) error {
+	return a.checkContest(ctx, userID, contestID, structs.ContestRoleCoOwner)
+}
+
+// CanManageContest checks that the user is the owner of the contest, only the owner can delete it and change its staff
+func (a *AuthorizerImp) CanManageContest(ctx context.Context, userID, contestID int64) error {
+	return a.checkContest(ctx, userID, contestID)
+}
+
+// CanTestContest checks that the user is the owner of the contest or is in its staff
+func (a *AuthorizerImp) CanTestContest(ctx context.Context, userID, contestID int64) error {
+	return a.checkContest(ctx, userID, contestID, structs.ContestRoleCoOwner, structs.ContestRoleTester)
+}
+
+// CanEditSubmission checks that the user can edit the contest of the submission, or its problem if it is
+// not a contest submission
+func (a *AuthorizerImp) CanEditSubmission(ctx context.Context, userID, submissionID int64) error {
+	submission, err := a.submissionsRepo.Get(ctx, submissionID)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't get submission")
+	}
+	if submission.ContestID != 0 {
+		err = a.CanEditContest(ctx, userID, submission.ContestID)
+		if !errors.Is(err, pkg.ErrForbidden) {
+			return err
+		}
+	}
+	return a.CanEditProblem(ctx, userID, submission.ProblemID)
+}
+
+// checkContest checks that the user is the owner of the contest or has one of the roles in it
+func (a *AuthorizerImp) checkContest(ctx context.Context, userID, contestID int64, roles ...string) error {
+	contest, err := a.contestsRepo.GetContest(ctx, contestID)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't get contest")
+	}
+	if contest.CreatedBy == userID {
+		return nil
+	}
+
+	if len(roles) != 0 {
+		role, err := a.contestsUsersRepo.GetStaffRole(ctx, contestID, userID)
+		if err != nil {
+			return errors.WithMessage(err, "couldn't get role of user in contest")
+		}
+		if slices.Contains(roles, role) {
+			return nil
+		}
+	}
+	return a.HasRole(ctx, userID, structs.RoleAdmin)
+}
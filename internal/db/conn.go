@@ -20,6 +20,10 @@ func pgxWrapper(ctx context.Context, c configs.SectionPostgres) (RepoWrapper, er
 	}
 
 	return func(ctx context.Context, r any) error {
+		if repo, ok := r.(*repos.Transactor); ok {
+			*repo = postgres.NewTransactor(pool)
+			return nil
+		}
 		if repo, ok := r.(*repos.UsersRepo); ok {
 			*repo, err = postgres.NewAuthRepo(ctx, pool)
 			return err
@@ -58,6 +62,10 @@ func sqlWrapper(ctx context.Context, c configs.SectionSQLDB) (RepoWrapper, error
 		return nil, err
 	}
 	return func(ctx context.Context, r any) error {
+		if repo, ok := r.(*repos.Transactor); ok {
+			*repo = sqlite.NewTransactor(conn)
+			return nil
+		}
 		if repo, ok := r.(*repos.UsersRepo); ok {
 			*repo, err = sqlite.NewAuthRepo(ctx, conn)
 			return err
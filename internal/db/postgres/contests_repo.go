@@ -29,6 +29,11 @@ func (c *ContestsMetadataRepoImp) Migrate(ctx context.Context) error {
 		created_at TIMESTAMP DEFAULT NOW(),
 		CONSTRAINT fk_created_by_contest FOREIGN KEY(created_by) REFERENCES users(id)
 	);
+	ALTER TABLE contests ADD COLUMN IF NOT EXISTS type varchar(10) NOT NULL DEFAULT 'score';
+	ALTER TABLE contests
+		ADD COLUMN IF NOT EXISTS freeze_time bigint NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS unfrozen boolean NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS revealed_until bigint NOT NULL DEFAULT 0;
 	`
 
 	_, err := c.conn.Exec(ctx, stmt)
@@ -44,12 +49,12 @@ func (c *ContestsMetadataRepoImp) InsertContest(ctx context.Context, contest str
 	var contestID int64
 	insertContestStmt := `
 			INSERT INTO contests(
-				created_by, title, start_time, duration) 
-			VALUES($1, $2, $3, $4) RETURNING id
+				created_by, title, start_time, duration, type, freeze_time) 
+			VALUES($1, $2, $3, $4, $5, $6) RETURNING id
 		`
 
 	err := c.conn.
-		QueryRow(ctx, insertContestStmt, contest.CreatedBy, contest.Title, contest.StartTime, contest.Duration).
+		QueryRow(ctx, insertContestStmt, contest.CreatedBy, contest.Title, contest.StartTime, contest.Duration, contest.Type, contest.FreezeTime).
 		Scan(&contestID)
 	if err != nil {
 		return 0, err
@@ -60,12 +65,12 @@ func (c *ContestsMetadataRepoImp) InsertContest(ctx context.Context, contest str
 
 func (c *ContestsMetadataRepoImp) GetContest(ctx context.Context, id int64) (structs.Contest, error) {
 	selectContestStmt := `
-		SELECT created_by, title, start_time, duration FROM contests WHERE id = $1
+		SELECT created_by, title, start_time, duration, type, freeze_time, unfrozen, revealed_until FROM contests WHERE id = $1
 	`
 
 	var contest structs.Contest
 	err := c.conn.QueryRow(ctx, selectContestStmt, id).
-		Scan(&contest.CreatedBy, &contest.Title, &contest.StartTime, &contest.Duration)
+		Scan(&contest.CreatedBy, &contest.Title, &contest.StartTime, &contest.Duration, &contest.Type, &contest.FreezeTime, &contest.Unfrozen, &contest.RevealedUntil)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return structs.Contest{}, pkg.ErrNotFound
 	} else if err != nil {
@@ -75,7 +80,7 @@ func (c *ContestsMetadataRepoImp) GetContest(ctx context.Context, id int64) (str
 	return contest, nil
 }
 
-func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, owned, getCount bool) ([]structs.Contest, int, error) {
+func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, owned, getCount bool) ([]structs.Contest, int, error) {
 	stmt := `
 	SELECT id, created_by, title, start_time, duration
 	`
@@ -85,12 +90,7 @@ func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending b
 
 	stmt = fmt.Sprintf("%s FROM contests", stmt)
 
-	now := time.Now().Unix()
-	if started {
-		stmt = fmt.Sprintf("%s WHERE start_time <= %d", stmt, now)
-	} else {
-		stmt = fmt.Sprintf("%s WHERE start_time > %d", stmt, now)
-	}
+	stmt = fmt.Sprintf("%s WHERE %s", stmt, statusCondition(status))
 
 	if owned {
 		stmt = fmt.Sprintf("%s AND created_by = $1", stmt)
@@ -192,6 +192,26 @@ func (c *ContestsMetadataRepoImp) UpdateContests(ctx context.Context, id int64,
 		}
 	}
 
+	if newContest.Type != "" {
+		stmt := `
+		UPDATE contests SET type = $1 WHERE id = $2
+		`
+		_, err := c.conn.Exec(ctx, stmt, newContest.Type, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newContest.FreezeTime != 0 {
+		stmt := `
+		UPDATE contests SET freeze_time = $1 WHERE id = $2
+		`
+		_, err := c.conn.Exec(ctx, stmt, newContest.FreezeTime, id)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -206,7 +226,7 @@ func (c *ContestsMetadataRepoImp) DeleteContest(ctx context.Context, id int64) e
 	return err
 }
 
-func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, getCount bool) ([]structs.Contest, int, error) {
+func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, getCount bool) ([]structs.Contest, int, error) {
 	stmt := `
 	SELECT id, created_by, title, start_time, duration
 	`
@@ -217,12 +237,7 @@ func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending
 
 	stmt = fmt.Sprintf("%s FROM contests JOIN contests_users ON contests_users.contest_id = contests.id WHERE contests_users.user_id = $1", stmt)
 
-	now := time.Now().Unix()
-	if started {
-		stmt = fmt.Sprintf("%s AND start_time <= %d", stmt, now)
-	} else {
-		stmt = fmt.Sprintf("%s AND start_time > %d", stmt, now)
-	}
+	stmt = fmt.Sprintf("%s AND %s", stmt, statusCondition(status))
 
 	stmt += " ORDER BY id "
 
@@ -286,3 +301,51 @@ func (c *ContestsMetadataRepoImp) HasStarted(ctx context.Context, id int64) (boo
 	}
 	return ans, nil
 }
+
+func (c *ContestsMetadataRepoImp) HasEnded(ctx context.Context, id int64) (bool, error) {
+	now := time.Now().Unix()
+	stmt := fmt.Sprintf(
+		"SELECT EXISTS(SELECT id FROM contests WHERE id = $1 AND start_time + duration <= %d)", now)
+
+	var ans bool
+	if err := c.conn.QueryRow(ctx, stmt, id).Scan(&ans); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = pkg.ErrNotFound
+		}
+		return false, err
+	}
+	return ans, nil
+}
+
+// statusCondition gives the condition of the contests with the status, the contests are upcoming by default
+func statusCondition(status string) string {
+	now := time.Now().Unix()
+	switch status {
+	case structs.ContestStatusRunning:
+		return fmt.Sprintf("start_time <= %d AND start_time + duration > %d", now, now)
+	case structs.ContestStatusFinished:
+		return fmt.Sprintf("start_time + duration <= %d", now)
+	case structs.ContestStatusStarted:
+		return fmt.Sprintf("start_time <= %d", now)
+	default:
+		return fmt.Sprintf("start_time > %d", now)
+	}
+}
+
+// RevealSubmission shows the results of hidden submissions of a frozen contest up to submissionID
+func (c *ContestsMetadataRepoImp) RevealSubmission(ctx context.Context, id, submissionID int64) error {
+	stmt := `
+	UPDATE contests SET revealed_until = $1 WHERE id = $2 AND revealed_until < $1
+	`
+	_, err := c.conn.Exec(ctx, stmt, submissionID, id)
+	return err
+}
+
+// Unfreeze shows the results of all submissions of a frozen contest
+func (c *ContestsMetadataRepoImp) Unfreeze(ctx context.Context, id int64) error {
+	stmt := `
+	UPDATE contests SET unfrozen = TRUE WHERE id = $1
+	`
+	_, err := c.conn.Exec(ctx, stmt, id)
+	return err
+}
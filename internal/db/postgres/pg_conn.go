@@ -2,8 +2,13 @@ package postgres
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ocontest/backend/internal/db/repos"
 	"github.com/ocontest/backend/pkg/configs"
+	"github.com/pkg/errors"
 )
 
 func NewConnectionPool(ctx context.Context, conf configs.SectionPostgres) (*pgxpool.Pool, error) {
@@ -23,3 +28,43 @@ func NewConnectionPool(ctx context.Context, conf configs.SectionPostgres) (*pgxp
 	err = pool.Ping(ctx)
 	return pool, err
 }
+
+type txKey struct{}
+
+// querier is implemented by both the pool and transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// conn gives the transaction of ctx if it's made by WithTx, otherwise the pool. Begin of a transaction
+// makes a savepoint, so repos can still use their own transactions in it
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+type TransactorImp struct {
+	conn *pgxpool.Pool
+}
+
+func NewTransactor(conn *pgxpool.Pool) repos.Transactor {
+	return &TransactorImp{conn: conn}
+}
+
+func (t *TransactorImp) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := conn(ctx, t.conn).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
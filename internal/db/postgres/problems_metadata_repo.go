@@ -50,6 +50,34 @@ func (a *ProblemsMetadataRepoImp) Migrate(ctx context.Context) (err error) {
 	`
 	_, err = a.conn.Exec(ctx, stmt)
 
+	stmt = `
+	ALTER TABLE problems
+	ADD COLUMN IF NOT EXISTS time_limit int NOT NULL DEFAULT 1000,
+	ADD COLUMN IF NOT EXISTS memory_limit int NOT NULL DEFAULT 256;
+	`
+	_, err = a.conn.Exec(ctx, stmt)
+
+	stmt = `
+	ALTER TABLE problems
+	ADD COLUMN IF NOT EXISTS checker_type varchar(20) NOT NULL DEFAULT 'exact',
+	ADD COLUMN IF NOT EXISTS checker_epsilon double precision NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS checker_language varchar(20) NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS checker_code TEXT NOT NULL DEFAULT '';
+	`
+	_, err = a.conn.Exec(ctx, stmt)
+
+	// users who can see a private problem besides its owner
+	stmt = `
+	CREATE TABLE IF NOT EXISTS problems_access(
+	    problem_id int NOT NULL,
+	    user_id int NOT NULL,
+	    PRIMARY KEY (problem_id, user_id),
+	    FOREIGN KEY(problem_id) REFERENCES problems(id) ON DELETE CASCADE,
+	    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	)
+	`
+	_, err = a.conn.Exec(ctx, stmt)
+
 	return err
 }
 
@@ -57,21 +85,21 @@ func (a *ProblemsMetadataRepoImp) InsertProblem(ctx context.Context, problem str
 
 	stmt := `
 	INSERT INTO problems(
-		created_by, title, document_id, hardness, is_private) 
-		VALUES($1, $2, $3, $4, $5) RETURNING id
+		created_by, title, document_id, hardness, is_private, time_limit, memory_limit) 
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id
 	`
 	var id int64
-	err := a.conn.QueryRow(ctx, stmt, problem.CreatedBy, problem.Title, problem.DocumentID, problem.Hardness, problem.IsPrivate).Scan(&id)
+	err := a.conn.QueryRow(ctx, stmt, problem.CreatedBy, problem.Title, problem.DocumentID, problem.Hardness, problem.IsPrivate, problem.TimeLimit, problem.MemoryLimit).Scan(&id)
 	return id, err
 }
 
 func (a *ProblemsMetadataRepoImp) GetProblem(ctx context.Context, id int64) (structs.Problem, error) {
 	stmt := `
-	SELECT created_by, title, document_id, solve_count, coalesce(hardness, -1) FROM problems WHERE id = $1
+	SELECT created_by, title, document_id, solve_count, coalesce(hardness, -1), time_limit, memory_limit, checker_type, is_private FROM problems WHERE id = $1
 	`
 	var problem structs.Problem
 	err := a.conn.QueryRow(ctx, stmt, id).Scan(
-		&problem.CreatedBy, &problem.Title, &problem.DocumentID, &problem.SolvedCount, &problem.Hardness)
+		&problem.CreatedBy, &problem.Title, &problem.DocumentID, &problem.SolvedCount, &problem.Hardness, &problem.TimeLimit, &problem.MemoryLimit, &problem.CheckerType, &problem.IsPrivate)
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = pkg.ErrNotFound
 	}
@@ -90,14 +118,16 @@ func (a *ProblemsMetadataRepoImp) GetProblemTitle(ctx context.Context, id int64)
 	return ans, err
 }
 
-func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn string, descending bool, limit, offset int, getCount bool) ([]structs.Problem, int, error) {
+func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn string, descending bool, limit, offset int, userID int64, getCount bool) ([]structs.Problem, int, error) {
 	stmt := `
 	SELECT id, created_by, title, document_id, solve_count, COALESCE(hardness, -1)
 	`
 	if getCount {
 		stmt = fmt.Sprintf("%s, COUNT(*) OVER() AS total_count", stmt)
 	}
-	stmt = fmt.Sprintf("%s FROM problems WHERE is_private = false ORDER BY", stmt)
+	// private problems are only listed for their owner and the users they are shared with
+	stmt = fmt.Sprintf(`%s FROM problems WHERE (is_private = false OR created_by = $1
+		OR id IN (SELECT problem_id FROM problems_access WHERE user_id = $1)) ORDER BY`, stmt)
 
 	colName, exists := SearchableColumns[searchColumn]
 	if !exists {
@@ -115,7 +145,7 @@ func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn
 		stmt = fmt.Sprintf("%s OFFSET %d", stmt, offset)
 	}
 
-	rows, err := a.conn.Query(ctx, stmt)
+	rows, err := a.conn.Query(ctx, stmt, userID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -139,7 +169,7 @@ func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn
 }
 
 // TODO: suitable query builder yada yada
-func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, title string, hardness int64) error {
+func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, title string, hardness, timeLimit, memoryLimit int64) error {
 	stmt := `
 	UPDATE problems SET
 	`
@@ -157,6 +187,20 @@ func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, t
 		args = append(args, hardness)
 		stmt = fmt.Sprintf("%s hardness = $%d", stmt, len(args))
 	}
+	if timeLimit != 0 {
+		if len(args) > 0 {
+			stmt += ","
+		}
+		args = append(args, timeLimit)
+		stmt = fmt.Sprintf("%s time_limit = $%d", stmt, len(args))
+	}
+	if memoryLimit != 0 {
+		if len(args) > 0 {
+			stmt += ","
+		}
+		args = append(args, memoryLimit)
+		stmt = fmt.Sprintf("%s memory_limit = $%d", stmt, len(args))
+	}
 	if len(args) == 0 {
 		return nil
 	}
@@ -171,6 +215,26 @@ func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, t
 	return err
 }
 
+func (a *ProblemsMetadataRepoImp) GetChecker(ctx context.Context, id int64) (structs.Checker, error) {
+	stmt := `
+	SELECT checker_type, checker_epsilon, checker_language, checker_code FROM problems WHERE id = $1
+	`
+	var checker structs.Checker
+	err := a.conn.QueryRow(ctx, stmt, id).Scan(&checker.Type, &checker.Epsilon, &checker.Language, &checker.Code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = pkg.ErrNotFound
+	}
+	return checker, err
+}
+
+func (a *ProblemsMetadataRepoImp) UpdateChecker(ctx context.Context, id int64, checker structs.Checker) error {
+	stmt := `
+	UPDATE problems SET checker_type = $1, checker_epsilon = $2, checker_language = $3, checker_code = $4 WHERE id = $5
+	`
+	_, err := a.conn.Exec(ctx, stmt, checker.Type, checker.Epsilon, checker.Language, checker.Code, id)
+	return err
+}
+
 func (a *ProblemsMetadataRepoImp) DeleteProblem(ctx context.Context, id int64) (string, error) {
 	stmt := `
 	DELETE FROM problems WHERE id = $1 RETURNING document_id
@@ -182,13 +246,62 @@ func (a *ProblemsMetadataRepoImp) DeleteProblem(ctx context.Context, id int64) (
 	}
 	return documentId, err
 }
-func (a *ProblemsMetadataRepoImp) AddSolve(ctx context.Context, id int64) error {
+func (a *ProblemsMetadataRepoImp) AddSolve(ctx context.Context, id int64, delta int) error {
 	stmt := `
-	UPDATE problems SET solve_count = solve_count + 1 WHERE id = $1
+	UPDATE problems SET solve_count = solve_count + $1 WHERE id = $2
 	`
-	_, err := a.conn.Exec(ctx, stmt, id)
+	_, err := conn(ctx, a.conn).Exec(ctx, stmt, delta, id)
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = pkg.ErrNotFound
 	}
 	return err
 }
+
+// GrantAccess lets the user see the problem when it is private
+func (a *ProblemsMetadataRepoImp) GrantAccess(ctx context.Context, id, userID int64) error {
+	stmt := `
+	INSERT INTO problems_access(problem_id, user_id) VALUES($1, $2) ON CONFLICT DO NOTHING
+	`
+	_, err := a.conn.Exec(ctx, stmt, id, userID)
+	return err
+}
+
+func (a *ProblemsMetadataRepoImp) RevokeAccess(ctx context.Context, id, userID int64) error {
+	stmt := `
+	DELETE FROM problems_access WHERE problem_id = $1 AND user_id = $2
+	`
+	_, err := a.conn.Exec(ctx, stmt, id, userID)
+	return err
+}
+
+func (a *ProblemsMetadataRepoImp) HasAccess(ctx context.Context, id, userID int64) (bool, error) {
+	stmt := `
+	SELECT EXISTS(SELECT 1 FROM problems_access WHERE problem_id = $1 AND user_id = $2)
+	`
+	var ans bool
+	err := a.conn.QueryRow(ctx, stmt, id, userID).Scan(&ans)
+	return ans, err
+}
+
+// ListAccess gives the users who the problem is shared with
+func (a *ProblemsMetadataRepoImp) ListAccess(ctx context.Context, id int64) ([]structs.ProblemAccess, error) {
+	stmt := `
+	SELECT users.id, users.username FROM problems_access JOIN users ON problems_access.user_id = users.id
+	WHERE problem_id = $1 ORDER BY users.id
+	`
+	rows, err := a.conn.Query(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ans := make([]structs.ProblemAccess, 0)
+	for rows.Next() {
+		var access structs.ProblemAccess
+		if err := rows.Scan(&access.UserID, &access.Username); err != nil {
+			return nil, err
+		}
+		ans = append(ans, access)
+	}
+	return ans, nil
+}
@@ -28,6 +28,15 @@ func (a *SubmissionRepoImp) Migrate(ctx context.Context) error {
 	stmts := []string{
 		"CREATE TYPE submission_status AS ENUM('unprocessed', 'processing', 'processed')",
 		"CREATE TYPE submission_language AS ENUM('python')",
+		"ALTER TYPE submission_language ADD VALUE IF NOT EXISTS 'c'",
+		"ALTER TYPE submission_language ADD VALUE IF NOT EXISTS 'cpp'",
+		"ALTER TYPE submission_language ADD VALUE IF NOT EXISTS 'go'",
+		"ALTER TYPE submission_language ADD VALUE IF NOT EXISTS 'java'",
+		"ALTER TYPE submission_language ADD VALUE IF NOT EXISTS 'rust'",
+		"ALTER TYPE submission_status ADD VALUE IF NOT EXISTS 'queued'",
+		"ALTER TYPE submission_status ADD VALUE IF NOT EXISTS 'compiling'",
+		"ALTER TYPE submission_status ADD VALUE IF NOT EXISTS 'running'",
+		"ALTER TYPE submission_status ADD VALUE IF NOT EXISTS 'judge_error'",
 		`
 		CREATE TABLE IF NOT EXISTS submissions(
 			id SERIAL,
@@ -49,6 +58,26 @@ func (a *SubmissionRepoImp) Migrate(ctx context.Context) error {
 			CONSTRAINT fk_problem_id FOREIGN KEY(problem_id) REFERENCES problems(id),
 			CONSTRAINT fk_user_id FOREIGN KEY(user_id) REFERENCES users(id),
 			CONSTRAINT fk_contest_id FOREIGN KEY(contest_id) REFERENCES contests(id)
+	)`,
+		`
+		ALTER TABLE submissions
+		ADD COLUMN IF NOT EXISTS status_message text NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS tests_done int NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS tests_total int NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS rejudge_id bigint,
+		ADD COLUMN IF NOT EXISTS pending_result_id varchar(70) NOT NULL DEFAULT ''
+	`,
+		`
+		CREATE TABLE IF NOT EXISTS rejudges(
+			id SERIAL PRIMARY KEY,
+			user_id bigint NOT NULL,
+			problem_id bigint NOT NULL DEFAULT 0,
+			contest_id bigint NOT NULL DEFAULT 0,
+			submission_id bigint NOT NULL DEFAULT 0,
+			total int NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+
+			CONSTRAINT fk_user_id FOREIGN KEY(user_id) REFERENCES users(id)
 	)`}
 
 	var err error
@@ -84,12 +113,12 @@ func (s *SubmissionRepoImp) Insert(ctx context.Context, submission structs.Submi
 
 func (s *SubmissionRepoImp) Get(ctx context.Context, id int64) (structs.SubmissionMetadata, error) {
 	stmt := `
-	SELECT id, problem_id, user_id, coalesce(contest_id, 0), file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at FROM submissions WHERE id = $1
+	SELECT id, problem_id, user_id, coalesce(contest_id, 0), file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at, status_message, tests_done, tests_total FROM submissions WHERE id = $1
 	`
 	var ans structs.SubmissionMetadata
 	var t time.Time
-	err := s.conn.QueryRow(ctx, stmt, id).Scan(
-		&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+	err := conn(ctx, s.conn).QueryRow(ctx, stmt, id).Scan(
+		&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t, &ans.StatusMessage, &ans.TestsDone, &ans.TestsTotal)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = pkg.ErrNotFound
@@ -149,9 +178,9 @@ func (s *SubmissionRepoImp) GetFinalSubmission(ctx context.Context, problemID, u
 	var t time.Time
 	var err error
 	if contestID != 0 {
-		err = s.conn.QueryRow(ctx, stmt, problemID, userID, contestID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+		err = conn(ctx, s.conn).QueryRow(ctx, stmt, problemID, userID, contestID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
 	} else {
-		err = s.conn.QueryRow(ctx, stmt, problemID, userID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+		err = conn(ctx, s.conn).QueryRow(ctx, stmt, problemID, userID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
 	}
 	ans.CreatedAT = t.Format(time.RFC3339)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -173,9 +202,9 @@ func (s *SubmissionRepoImp) UpdateJudgeResults(ctx context.Context, problemID, u
 	var err error
 	if isFinal {
 		if contestID != 0 {
-			_, err = s.conn.Exec(ctx, stmt, problemID, userID, contestID)
+			_, err = conn(ctx, s.conn).Exec(ctx, stmt, problemID, userID, contestID)
 		} else {
-			_, err = s.conn.Exec(ctx, stmt, problemID, userID)
+			_, err = conn(ctx, s.conn).Exec(ctx, stmt, problemID, userID)
 		}
 		if err != nil {
 			return err
@@ -185,10 +214,157 @@ func (s *SubmissionRepoImp) UpdateJudgeResults(ctx context.Context, problemID, u
 	stmt = `
 	UPDATE submissions SET status = 'processed', score = $1, judge_result_id = $2, is_final = $3 WHERE id = $4
 	`
-	_, err = s.conn.Exec(ctx, stmt, score, docID, isFinal, submissionID)
+	_, err = conn(ctx, s.conn).Exec(ctx, stmt, score, docID, isFinal, submissionID)
+	return err
+}
+
+// UpdateStatus records the progress of judging, a judged submission or one with a judge error is never changed by it.
+// since progress is reported asynchronously, this stops late updates from overwriting the final status
+func (s *SubmissionRepoImp) UpdateStatus(ctx context.Context, submissionID int64, status, message string, testsDone, testsTotal int) error {
+	stmt := `
+	UPDATE submissions SET status = $1, status_message = $2, tests_done = $3, tests_total = $4
+	WHERE id = $5 AND status NOT IN ('processed', 'judge_error')
+	`
+	_, err := s.conn.Exec(ctx, stmt, status, message, testsDone, testsTotal, submissionID)
 	return err
 }
 
+func (s *SubmissionRepoImp) SetPendingResult(ctx context.Context, submissionID int64, judgeResultID string) error {
+	stmt := `
+	UPDATE submissions SET pending_result_id = $1 WHERE id = $2
+	`
+	_, err := conn(ctx, s.conn).Exec(ctx, stmt, judgeResultID, submissionID)
+	return err
+}
+
+func (s *SubmissionRepoImp) GetPendingResult(ctx context.Context, submissionID int64) (string, error) {
+	stmt := `
+	SELECT pending_result_id FROM submissions WHERE id = $1
+	`
+	var ans string
+	err := conn(ctx, s.conn).QueryRow(ctx, stmt, submissionID).Scan(&ans)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", pkg.ErrNotFound
+	}
+	return ans, err
+}
+
+// UpdateFinalSubmission marks the submission with the highest score as final, the last one is taken if there are more.
+// the final submission is returned
+func (s *SubmissionRepoImp) UpdateFinalSubmission(ctx context.Context, problemID, userID, contestID int64) (structs.SubmissionMetadata, error) {
+	args := []interface{}{problemID, userID}
+	cond := "problem_id = $1 AND user_id = $2"
+	if contestID != 0 {
+		args = append(args, contestID)
+		cond += " AND contest_id = $3"
+	}
+
+	tx, err := conn(ctx, s.conn).Begin(ctx)
+	if err != nil {
+		return structs.SubmissionMetadata{}, errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "UPDATE submissions SET is_final = false WHERE "+cond, args...)
+	if err != nil {
+		return structs.SubmissionMetadata{}, errors.Wrap(err, "error on clearing final submissions")
+	}
+
+	stmt := fmt.Sprintf(`
+	UPDATE submissions SET is_final = true WHERE id = (
+		SELECT id FROM submissions WHERE %s ORDER BY score DESC, id DESC LIMIT 1
+	) RETURNING id, problem_id, user_id, coalesce(contest_id, 0), file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at
+	`, cond)
+	var ans structs.SubmissionMetadata
+	var t time.Time
+	err = tx.QueryRow(ctx, stmt, args...).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.ContestID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ans, pkg.ErrNotFound
+	}
+	if err != nil {
+		return ans, errors.Wrap(err, "error on setting final submission")
+	}
+	ans.CreatedAT = t.Format(time.RFC3339)
+	return ans, errors.WithStack(tx.Commit(ctx))
+}
+
+// InsertRejudge creates the rejudge job and resets the judged submissions it covers, so they can be dispatched again.
+// ids of the reset submissions are returned, submissions which are being judged are left as they are
+func (s *SubmissionRepoImp) InsertRejudge(ctx context.Context, rejudge structs.Rejudge) (int64, []int64, error) {
+	tx, err := conn(ctx, s.conn).Begin(ctx)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+	INSERT INTO rejudges(user_id, problem_id, contest_id, submission_id) VALUES($1, $2, $3, $4) RETURNING id
+	`, rejudge.UserID, rejudge.ProblemID, rejudge.ContestID, rejudge.SubmissionID).Scan(&id)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on inserting rejudge")
+	}
+
+	args := []interface{}{id}
+	var cond string
+	switch {
+	case rejudge.SubmissionID != 0:
+		args = append(args, rejudge.SubmissionID)
+		cond = "id = $2"
+	case rejudge.ContestID != 0:
+		args = append(args, rejudge.ContestID)
+		cond = "contest_id = $2"
+	default:
+		args = append(args, rejudge.ProblemID)
+		cond = "problem_id = $2"
+	}
+	stmt := fmt.Sprintf(`
+	UPDATE submissions SET status = 'unprocessed', status_message = '', tests_done = 0, judge_result_id = '', pending_result_id = '', rejudge_id = $1
+	WHERE %s AND status IN ('processed', 'judge_error') RETURNING id
+	`, cond)
+	rows, err := tx.Query(ctx, stmt, args...)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on resetting submissions")
+	}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var submissionID int64
+		if err := rows.Scan(&submissionID); err != nil {
+			rows.Close()
+			return 0, nil, errors.Wrap(err, "error on reading row")
+		}
+		ids = append(ids, submissionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, errors.Wrap(err, "error on resetting submissions")
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE rejudges SET total = $1 WHERE id = $2`, len(ids), id)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on updating total of rejudge")
+	}
+	return id, ids, errors.WithStack(tx.Commit(ctx))
+}
+
+func (s *SubmissionRepoImp) GetRejudge(ctx context.Context, id int64) (structs.Rejudge, error) {
+	stmt := `
+	SELECT rejudges.id, rejudges.user_id, rejudges.problem_id, rejudges.contest_id, rejudges.submission_id, rejudges.total, rejudges.created_at,
+		COUNT(submissions.id) FILTER (WHERE submissions.status = 'processed'),
+		COUNT(submissions.id) FILTER (WHERE submissions.status = 'judge_error')
+	FROM rejudges LEFT JOIN submissions ON submissions.rejudge_id = rejudges.id
+	WHERE rejudges.id = $1 GROUP BY rejudges.id
+	`
+	var ans structs.Rejudge
+	var t time.Time
+	err := s.conn.QueryRow(ctx, stmt, id).Scan(&ans.ID, &ans.UserID, &ans.ProblemID, &ans.ContestID, &ans.SubmissionID, &ans.Total, &t, &ans.Judged, &ans.Failed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ans, pkg.ErrNotFound
+	}
+	ans.CreatedAt = t.Format(time.RFC3339)
+	return ans, err
+}
+
 func (s *SubmissionRepoImp) ListSubmissions(ctx context.Context, problemID, userID, contestID int64, descending bool, limit, offset int, getCount bool) ([]structs.SubmissionMetadata, int, error) {
 	args := make([]interface{}, 0)
 
@@ -243,7 +419,7 @@ func (s *SubmissionRepoImp) ListSubmissions(ctx context.Context, problemID, user
 		stmt = fmt.Sprintf("%s OFFSET $%d", stmt, len(args))
 	}
 
-	rows, err := s.conn.Query(ctx, stmt, args...)
+	rows, err := conn(ctx, s.conn).Query(ctx, stmt, args...)
 
 	if err != nil {
 		return nil, 0, err
@@ -26,6 +26,7 @@ func (a *TestCaseRepoImp) Migrate(ctx context.Context) error {
 			
 			input text not null ,
 			output text not null ,
+			group_id bigint not null default 0,
 
 			unique(id),
 			primary key (problem_id, id),
@@ -34,13 +35,37 @@ func (a *TestCaseRepoImp) Migrate(ctx context.Context) error {
 	)`
 
 	_, err := a.conn.Exec(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	stmt = `
+	ALTER TABLE testcases
+	ADD COLUMN IF NOT EXISTS group_id bigint NOT NULL DEFAULT 0;
+	`
+	_, err = a.conn.Exec(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
+	stmt = `
+		CREATE TABLE IF NOT EXISTS test_groups(
+			problem_id bigint not null,
+			id bigint not null,
+			points int not null,
+
+			primary key (problem_id, id),
+
+			CONSTRAINT fk_problem_id FOREIGN KEY(problem_id) REFERENCES problems(id)
+	)`
+	_, err = a.conn.Exec(ctx, stmt)
 
 	return err
 }
 
 func (t *TestCaseRepoImp) Insert(ctx context.Context, testCase structs.Testcase) (id int64, err error) {
-	stmt := `INSERT INTO testcases(problem_id, input, output) VALUES($1, $2, $3) RETURNING id`
-	err = t.conn.QueryRow(ctx, stmt, testCase.ProblemID, testCase.Input, testCase.ExpectedOutput).Scan(&id)
+	stmt := `INSERT INTO testcases(problem_id, input, output, group_id) VALUES($1, $2, $3, $4) RETURNING id`
+	err = t.conn.QueryRow(ctx, stmt, testCase.ProblemID, testCase.Input, testCase.ExpectedOutput, testCase.GroupID).Scan(&id)
 	if err != nil {
 		err = errors.Wrap(err, "error on inserting to testcase repos")
 		return
@@ -51,16 +76,16 @@ func (t *TestCaseRepoImp) Insert(ctx context.Context, testCase structs.Testcase)
 // not sure if we need it
 func (t *TestCaseRepoImp) GetByID(ctx context.Context, id int64) (ans structs.Testcase, err error) {
 	stmt := `
-	SELECT id, problem_id, input, output FROM testcases WHERE id = $1
+	SELECT id, problem_id, input, output, group_id FROM testcases WHERE id = $1
 	`
-	err = t.conn.QueryRow(ctx, stmt, id).Scan(&ans.ID, &ans.ProblemID, &ans.Input, &ans.ExpectedOutput)
+	err = t.conn.QueryRow(ctx, stmt, id).Scan(&ans.ID, &ans.ProblemID, &ans.Input, &ans.ExpectedOutput, &ans.GroupID)
 	return ans, err
 }
 
 // GetAllTestsOfProblem since our first part of primary key is problem id, there will be no performance issue
 func (t *TestCaseRepoImp) GetAllTestsOfProblem(ctx context.Context, problemID int64) ([]structs.Testcase, error) {
 	stmt := `
-	SELECT id, problem_id, input, output FROM testcases WHERE problem_id = $1
+	SELECT id, problem_id, input, output, group_id FROM testcases WHERE problem_id = $1 ORDER BY id
 	`
 	rows, err := t.conn.Query(ctx, stmt, problemID)
 	if err != nil {
@@ -72,7 +97,7 @@ func (t *TestCaseRepoImp) GetAllTestsOfProblem(ctx context.Context, problemID in
 	ans := make([]structs.Testcase, 0)
 	for rows.Next() {
 		var newTestcase structs.Testcase
-		err := rows.Scan(&newTestcase.ID, &newTestcase.ProblemID, &newTestcase.Input, &newTestcase.ExpectedOutput)
+		err := rows.Scan(&newTestcase.ID, &newTestcase.ProblemID, &newTestcase.Input, &newTestcase.ExpectedOutput, &newTestcase.GroupID)
 		if err != nil {
 			err = errors.Wrap(err, "error on reading row")
 			return nil, errors.WithStack(err)
@@ -82,3 +107,55 @@ func (t *TestCaseRepoImp) GetAllTestsOfProblem(ctx context.Context, problemID in
 
 	return ans, nil
 }
+
+// ReplaceTests replaces the tests and the test groups of a problem
+func (t *TestCaseRepoImp) ReplaceTests(ctx context.Context, problemID int64, testCases []structs.Testcase, groups []structs.TestGroup) error {
+	tx, err := t.conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM testcases WHERE problem_id = $1`, problemID)
+	if err != nil {
+		return errors.Wrap(err, "error on deleting old testcases")
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM test_groups WHERE problem_id = $1`, problemID)
+	if err != nil {
+		return errors.Wrap(err, "error on deleting old test groups")
+	}
+	for _, tc := range testCases {
+		_, err = tx.Exec(ctx, `INSERT INTO testcases(problem_id, input, output, group_id) VALUES($1, $2, $3, $4)`,
+			problemID, tc.Input, tc.ExpectedOutput, tc.GroupID)
+		if err != nil {
+			return errors.Wrap(err, "error on inserting testcase")
+		}
+	}
+	for _, g := range groups {
+		_, err = tx.Exec(ctx, `INSERT INTO test_groups(problem_id, id, points) VALUES($1, $2, $3)`, problemID, g.ID, g.Points)
+		if err != nil {
+			return errors.Wrap(err, "error on inserting test group")
+		}
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
+
+func (t *TestCaseRepoImp) GetGroups(ctx context.Context, problemID int64) ([]structs.TestGroup, error) {
+	stmt := `
+	SELECT id, points FROM test_groups WHERE problem_id = $1 ORDER BY id
+	`
+	rows, err := t.conn.Query(ctx, stmt, problemID)
+	if err != nil {
+		return nil, errors.WithStack(errors.Wrap(err, "error on executing query"))
+	}
+	defer rows.Close()
+	ans := make([]structs.TestGroup, 0)
+	for rows.Next() {
+		var g structs.TestGroup
+		if err := rows.Scan(&g.ID, &g.Points); err != nil {
+			return nil, errors.WithStack(errors.Wrap(err, "error on reading row"))
+		}
+		ans = append(ans, g)
+	}
+	return ans, nil
+}
@@ -29,7 +29,10 @@ func (a *UsersRepoImp) Migrate(ctx context.Context) error {
 	    is_verified boolean DEFAULT false,
 	    UNIQUE (username),
 	    UNIQUE (email)
-	)
+	);
+	-- users from before roles could create problems and contests, new users are contestants
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'setter';
+	ALTER TABLE users ALTER COLUMN role SET DEFAULT 'contestant'
 	`
 
 	_, err := a.conn.Exec(ctx, stmt)
@@ -54,19 +57,19 @@ func (a *UsersRepoImp) VerifyUser(ctx context.Context, userID int64) error {
 
 func (a *UsersRepoImp) GetByUsername(ctx context.Context, username string) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE username = $1 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE username = $1 
 	`
 	var user structs.User
-	err := a.conn.QueryRow(ctx, stmt, username).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRow(ctx, stmt, username).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
 func (a *UsersRepoImp) GetByID(ctx context.Context, userID int64) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE id = $1 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE id = $1 
 	`
 	var user structs.User
-	err := a.conn.QueryRow(ctx, stmt, userID).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRow(ctx, stmt, userID).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
@@ -81,10 +84,10 @@ func (a *UsersRepoImp) GetUsername(ctx context.Context, userID int64) (string, e
 
 func (a *UsersRepoImp) GetByEmail(ctx context.Context, email string) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE email = $1 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE email = $1 
 	`
 	var user structs.User
-	err := a.conn.QueryRow(ctx, stmt, email).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRow(ctx, stmt, email).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
@@ -104,13 +107,16 @@ func (a *UsersRepoImp) UpdateUser(ctx context.Context, user structs.User) error
 	}
 	if user.Email != "" {
 		args = append(args, user.Email)
-		if len(args) > 1 {
+		if len(args) > 2 {
 			stmt += ","
 		}
 		stmt += fmt.Sprintf("email = $%d", len(args))
 	}
 	if user.EncryptedPassword != "" {
 		args = append(args, user.EncryptedPassword)
+		if len(args) > 2 {
+			stmt += ","
+		}
 		stmt += fmt.Sprintf("password = $%d", len(args))
 	}
 	stmt += " WHERE id = $1"
@@ -121,3 +127,42 @@ func (a *UsersRepoImp) UpdateUser(ctx context.Context, user structs.User) error
 	_, err := a.conn.Exec(ctx, stmt, args...)
 	return err
 }
+
+// SetRole changes the role of the user, it is one of structs.Roles
+func (a *UsersRepoImp) SetRole(ctx context.Context, userID int64, role string) error {
+	stmt := `
+	UPDATE users SET role = $1 WHERE id = $2
+	`
+	_, err := a.conn.Exec(ctx, stmt, role, userID)
+	return err
+}
+
+// UpdatePassword replaces the stored password of the user, it should be hashed before
+func (a *UsersRepoImp) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	stmt := `
+	UPDATE users SET password = $1 WHERE id = $2
+	`
+	_, err := a.conn.Exec(ctx, stmt, password, userID)
+	return err
+}
+
+func (a *UsersRepoImp) ListUsers(ctx context.Context) ([]structs.User, error) {
+	stmt := `
+	SELECT id, username, password, email, is_verified, role FROM users ORDER BY id
+	`
+	rows, err := a.conn.Query(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ans := make([]structs.User, 0)
+	for rows.Next() {
+		var user structs.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role); err != nil {
+			return nil, err
+		}
+		ans = append(ans, user)
+	}
+	return ans, nil
+}
@@ -6,6 +6,12 @@ import (
 	"github.com/ocontest/backend/pkg/structs"
 )
 
+// Transactor runs fn in a transaction, the repos of the same database take part in it when they are called
+// with the ctx given to fn. the transaction is committed if fn returns nil
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type UsersRepo interface {
 	InsertUser(ctx context.Context, user structs.User) (int64, error)
 	VerifyUser(ctx context.Context, userID int64) error
@@ -14,26 +20,38 @@ type UsersRepo interface {
 	GetUsername(ctx context.Context, userID int64) (string, error)
 	GetByEmail(ctx context.Context, email string) (structs.User, error)
 	UpdateUser(ctx context.Context, user structs.User) error
+	SetRole(ctx context.Context, userID int64, role string) error
+	UpdatePassword(ctx context.Context, userID int64, password string) error
+	ListUsers(ctx context.Context) ([]structs.User, error)
 }
 
 type ProblemsMetadataRepo interface {
 	InsertProblem(ctx context.Context, problem structs.Problem) (int64, error)
 	GetProblem(ctx context.Context, id int64) (structs.Problem, error)
 	GetProblemTitle(ctx context.Context, id int64) (string, error)
-	ListProblems(ctx context.Context, searchCol string, descending bool, limit, offset int, getCount bool) ([]structs.Problem, int, error)
-	UpdateProblem(ctx context.Context, id int64, title string, hardness int64) error
+	ListProblems(ctx context.Context, searchCol string, descending bool, limit, offset int, userID int64, getCount bool) ([]structs.Problem, int, error)
+	UpdateProblem(ctx context.Context, id int64, title string, hardness, timeLimit, memoryLimit int64) error
+	GetChecker(ctx context.Context, id int64) (structs.Checker, error)
+	UpdateChecker(ctx context.Context, id int64, checker structs.Checker) error
 	DeleteProblem(ctx context.Context, id int64) (string, error)
-	AddSolve(ctx context.Context, id int64) error
+	AddSolve(ctx context.Context, id int64, delta int) error
+	GrantAccess(ctx context.Context, id, userID int64) error
+	RevokeAccess(ctx context.Context, id, userID int64) error
+	HasAccess(ctx context.Context, id, userID int64) (bool, error)
+	ListAccess(ctx context.Context, id int64) ([]structs.ProblemAccess, error)
 }
 
 type ContestsMetadataRepo interface {
 	InsertContest(ctx context.Context, contest structs.Contest) (int64, error)
 	GetContest(ctx context.Context, id int64) (structs.Contest, error)
-	ListContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, owned, getCount bool) ([]structs.Contest, int, error)
-	ListMyContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, getCount bool) ([]structs.Contest, int, error)
+	ListContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, owned, getCount bool) ([]structs.Contest, int, error)
+	ListMyContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, getCount bool) ([]structs.Contest, int, error)
 	UpdateContests(ctx context.Context, id int64, newContest structs.RequestUpdateContest) error
 	DeleteContest(ctx context.Context, id int64) error
 	HasStarted(ctx context.Context, id int64) (bool, error)
+	HasEnded(ctx context.Context, id int64) (bool, error)
+	RevealSubmission(ctx context.Context, id, submissionID int64) error
+	Unfreeze(ctx context.Context, id int64) error
 }
 
 type ProblemDescriptionsRepo interface {
@@ -48,12 +66,15 @@ type ContestsProblemsRepo interface {
 	GetContestProblems(ctx context.Context, id int64) ([]int64, error)
 	RemoveProblemFromContest(ctx context.Context, contestID, problemID int64) error
 	HasProblem(ctx context.Context, contestID, problemID int64) (bool, error)
+	GetProblemContests(ctx context.Context, problemID int64) ([]int64, error)
 }
 
 type TestCaseRepo interface {
 	Insert(ctx context.Context, testCase structs.Testcase) (int64, error)
 	GetByID(ctx context.Context, id int64) (structs.Testcase, error)
 	GetAllTestsOfProblem(ctx context.Context, problemID int64) ([]structs.Testcase, error)
+	ReplaceTests(ctx context.Context, problemID int64, testCases []structs.Testcase, groups []structs.TestGroup) error
+	GetGroups(ctx context.Context, problemID int64) ([]structs.TestGroup, error)
 }
 
 type SubmissionMetadataRepo interface {
@@ -62,6 +83,14 @@ type SubmissionMetadataRepo interface {
 	GetByProblem(ctx context.Context, problemID int64) ([]structs.SubmissionMetadata, error)
 	GetFinalSubmission(ctx context.Context, problemID, userID, contestID int64) (structs.SubmissionMetadata, error)
 	UpdateJudgeResults(ctx context.Context, problemID, userID, contestID, submissionID int64, judgeResultID string, score int, isFinal bool) error
+	UpdateStatus(ctx context.Context, submissionID int64, status, message string, testsDone, testsTotal int) error
+	UpdateFinalSubmission(ctx context.Context, problemID, userID, contestID int64) (structs.SubmissionMetadata, error)
+	// SetPendingResult keeps the id of a judge result that is stored but not processed yet, so a retry of processing
+	// doesn't store it again. it is cleared by InsertRejudge
+	SetPendingResult(ctx context.Context, submissionID int64, judgeResultID string) error
+	GetPendingResult(ctx context.Context, submissionID int64) (string, error)
+	InsertRejudge(ctx context.Context, rejudge structs.Rejudge) (int64, []int64, error)
+	GetRejudge(ctx context.Context, id int64) (structs.Rejudge, error)
 	ListSubmissions(ctx context.Context, problemID, userID, contestID int64, descending bool, limit, offset int, getCount bool) ([]structs.SubmissionMetadata, int, error)
 }
 
@@ -77,4 +106,13 @@ type ContestsUsersRepo interface {
 	ListUsersByScore(ctx context.Context, contestID int64, limit, offset int) ([]structs.User, error)
 	GetContestUsersCount(ctx context.Context, contestID int64) (int, error)
 	AddUserScore(ctx context.Context, userID, contestID int64, delta int) error
+	UpdateStanding(ctx context.Context, standing structs.ContestStanding) error
+	GetStandings(ctx context.Context, contestID int64) ([]structs.ContestStanding, error)
+	AddVirtual(ctx context.Context, contestID, userID, startOffset int64) error
+	GetStartOffset(ctx context.Context, contestID, userID int64) (int64, error)
+	ListParticipants(ctx context.Context, contestID int64) ([]structs.ContestParticipant, error)
+	AddStaff(ctx context.Context, contestID, userID int64, role string) error
+	DeleteStaff(ctx context.Context, contestID, userID int64) error
+	GetStaffRole(ctx context.Context, contestID, userID int64) (string, error)
+	ListStaff(ctx context.Context, contestID int64) ([]structs.ContestStaff, error)
 }
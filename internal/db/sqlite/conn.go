@@ -9,6 +9,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 	"slices"
 
+	"github.com/ocontest/backend/internal/db/repos"
 	"github.com/ocontest/backend/pkg"
 	"github.com/pkg/errors"
 )
@@ -28,3 +29,53 @@ func NewDBConn(ctx context.Context, dbType, connStr string) (*sql.DB, error) {
 	err = db.PingContext(ctx)
 	return db, err
 }
+
+type txKey struct{}
+
+// executor is implemented by both the db and transactions
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn gives the transaction of ctx if it's made by WithTx, otherwise the db
+func conn(ctx context.Context, db *sql.DB) executor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx runs fn in the transaction of ctx, or in a new one if ctx has none. sql transactions can't be nested,
+// so a transaction of ctx is left to be committed by its owner
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+type TransactorImp struct {
+	conn *sql.DB
+}
+
+func NewTransactor(conn *sql.DB) repos.Transactor {
+	return &TransactorImp{conn: conn}
+}
+
+func (t *TransactorImp) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return inTx(ctx, t.conn, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
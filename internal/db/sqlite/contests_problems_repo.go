@@ -104,3 +104,26 @@ func (c *ContestsProblemsMetadataRepoImp) HasProblem(ctx context.Context, contes
 	}
 	return ans, nil
 }
+
+// GetProblemContests gives the contests which include the problem
+func (c *ContestsProblemsMetadataRepoImp) GetProblemContests(ctx context.Context, problemID int64) ([]int64, error) {
+	stmt := `
+		SELECT contest_id FROM contest_problems WHERE problem_id = $
+	`
+
+	rows, err := c.conn.QueryContext(ctx, stmt, problemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]int64, 0)
+	for rows.Next() {
+		var contestID int64
+		if err := rows.Scan(&contestID); err != nil {
+			return nil, err
+		}
+		result = append(result, contestID)
+	}
+	return result, nil
+}
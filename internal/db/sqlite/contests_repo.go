@@ -27,6 +27,10 @@ func (c *ContestsMetadataRepoImp) Migrate(ctx context.Context) error {
 	    start_time bigint NOT NULL,
 	    duration int NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		type varchar(10) NOT NULL DEFAULT 'score',
+		freeze_time bigint NOT NULL DEFAULT 0,
+		unfrozen boolean NOT NULL DEFAULT FALSE,
+		revealed_until bigint NOT NULL DEFAULT 0,
 		CONSTRAINT fk_created_by_contest FOREIGN KEY(created_by) REFERENCES users(id)
 	);
 	`
@@ -44,11 +48,11 @@ func (c *ContestsMetadataRepoImp) InsertContest(ctx context.Context, contest str
 	var contestID int64
 	insertContestStmt := `
 			INSERT INTO contests(
-				created_by, title, start_time, duration) 
-			VALUES($, $, $, $) RETURNING id
+				created_by, title, start_time, duration, type, freeze_time) 
+			VALUES($, $, $, $, $, $) RETURNING id
 		`
 
-	err := c.conn.QueryRowContext(ctx, insertContestStmt, contest.CreatedBy, contest.Title, contest.StartTime, contest.Duration).
+	err := c.conn.QueryRowContext(ctx, insertContestStmt, contest.CreatedBy, contest.Title, contest.StartTime, contest.Duration, contest.Type, contest.FreezeTime).
 		Scan(&contestID)
 	if err != nil {
 		return 0, err
@@ -59,12 +63,12 @@ func (c *ContestsMetadataRepoImp) InsertContest(ctx context.Context, contest str
 
 func (c *ContestsMetadataRepoImp) GetContest(ctx context.Context, id int64) (structs.Contest, error) {
 	selectContestStmt := `
-		SELECT created_by, title, start_time, duration FROM contests WHERE id = $
+		SELECT created_by, title, start_time, duration, type, freeze_time, unfrozen, revealed_until FROM contests WHERE id = $
 	`
 
 	var contest structs.Contest
 	err := c.conn.QueryRowContext(ctx, selectContestStmt, id).
-		Scan(&contest.CreatedBy, &contest.Title, &contest.StartTime, &contest.Duration)
+		Scan(&contest.CreatedBy, &contest.Title, &contest.StartTime, &contest.Duration, &contest.Type, &contest.FreezeTime, &contest.Unfrozen, &contest.RevealedUntil)
 	if errors.Is(err, sql.ErrNoRows) {
 		return structs.Contest{}, pkg.ErrNotFound
 	} else if err != nil {
@@ -74,7 +78,7 @@ func (c *ContestsMetadataRepoImp) GetContest(ctx context.Context, id int64) (str
 	return contest, nil
 }
 
-func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, owned, getCount bool) ([]structs.Contest, int, error) {
+func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, owned, getCount bool) ([]structs.Contest, int, error) {
 	stmt := `
 	SELECT id, created_by, title, start_time, duration
 	`
@@ -84,12 +88,7 @@ func (c *ContestsMetadataRepoImp) ListContests(ctx context.Context, descending b
 
 	stmt = fmt.Sprintf("%s FROM contests", stmt)
 
-	now := time.Now().Unix()
-	if started {
-		stmt = fmt.Sprintf("%s WHERE start_time <= %d", stmt, now)
-	} else {
-		stmt = fmt.Sprintf("%s WHERE start_time > %d", stmt, now)
-	}
+	stmt = fmt.Sprintf("%s WHERE %s", stmt, statusCondition(status))
 
 	if owned {
 		stmt = fmt.Sprintf("%s AND created_by = $", stmt)
@@ -191,6 +190,26 @@ func (c *ContestsMetadataRepoImp) UpdateContests(ctx context.Context, id int64,
 		}
 	}
 
+	if newContest.Type != "" {
+		stmt := `
+		UPDATE contests SET type = $ WHERE id = $
+		`
+		_, err := c.conn.ExecContext(ctx, stmt, newContest.Type, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newContest.FreezeTime != 0 {
+		stmt := `
+		UPDATE contests SET freeze_time = $ WHERE id = $
+		`
+		_, err := c.conn.ExecContext(ctx, stmt, newContest.FreezeTime, id)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -205,7 +224,7 @@ func (c *ContestsMetadataRepoImp) DeleteContest(ctx context.Context, id int64) e
 	return err
 }
 
-func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending bool, limit, offset int, started bool, userID int64, getCount bool) ([]structs.Contest, int, error) {
+func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending bool, limit, offset int, status string, userID int64, getCount bool) ([]structs.Contest, int, error) {
 	stmt := `
 	SELECT id, created_by, title, start_time, duration
 	`
@@ -216,12 +235,7 @@ func (c *ContestsMetadataRepoImp) ListMyContests(ctx context.Context, descending
 
 	stmt = fmt.Sprintf("%s FROM contests JOIN contests_users ON contests_users.contest_id = contests.id WHERE contests_users.user_id = $", stmt)
 
-	now := time.Now().Unix()
-	if started {
-		stmt = fmt.Sprintf("%s AND start_time <= %d", stmt, now)
-	} else {
-		stmt = fmt.Sprintf("%s AND start_time > %d", stmt, now)
-	}
+	stmt = fmt.Sprintf("%s AND %s", stmt, statusCondition(status))
 
 	stmt += " ORDER BY id "
 
@@ -285,3 +299,51 @@ func (c *ContestsMetadataRepoImp) HasStarted(ctx context.Context, id int64) (boo
 	}
 	return ans, nil
 }
+
+func (c *ContestsMetadataRepoImp) HasEnded(ctx context.Context, id int64) (bool, error) {
+	now := time.Now().Unix()
+	stmt := fmt.Sprintf(
+		"SELECT EXISTS(SELECT id FROM contests WHERE id = $ AND start_time + duration <= %d)", now)
+
+	var ans bool
+	if err := c.conn.QueryRowContext(ctx, stmt, id).Scan(&ans); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = pkg.ErrNotFound
+		}
+		return false, err
+	}
+	return ans, nil
+}
+
+// statusCondition gives the condition of the contests with the status, the contests are upcoming by default
+func statusCondition(status string) string {
+	now := time.Now().Unix()
+	switch status {
+	case structs.ContestStatusRunning:
+		return fmt.Sprintf("start_time <= %d AND start_time + duration > %d", now, now)
+	case structs.ContestStatusFinished:
+		return fmt.Sprintf("start_time + duration <= %d", now)
+	case structs.ContestStatusStarted:
+		return fmt.Sprintf("start_time <= %d", now)
+	default:
+		return fmt.Sprintf("start_time > %d", now)
+	}
+}
+
+// RevealSubmission shows the results of hidden submissions of a frozen contest up to submissionID
+func (c *ContestsMetadataRepoImp) RevealSubmission(ctx context.Context, id, submissionID int64) error {
+	stmt := `
+	UPDATE contests SET revealed_until = $ WHERE id = $ AND revealed_until < $
+	`
+	_, err := c.conn.ExecContext(ctx, stmt, submissionID, id, submissionID)
+	return err
+}
+
+// Unfreeze shows the results of all submissions of a frozen contest
+func (c *ContestsMetadataRepoImp) Unfreeze(ctx context.Context, id int64) error {
+	stmt := `
+	UPDATE contests SET unfrozen = TRUE WHERE id = $
+	`
+	_, err := c.conn.ExecContext(ctx, stmt, id)
+	return err
+}
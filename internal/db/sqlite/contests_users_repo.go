@@ -21,6 +21,27 @@ func (c *ContestsUsersRepoImp) Migrate(ctx context.Context) error {
 		contest_id int NOT NULL,
 		user_id int NOT NULL,
 		score float DEFAULT 0,
+		start_offset bigint NOT NULL DEFAULT 0,
+		PRIMARY KEY (contest_id, user_id),
+		FOREIGN KEY(contest_id) REFERENCES contests(id) ON DELETE CASCADE,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE TABLE IF NOT EXISTS contest_standings (
+		contest_id int NOT NULL,
+		user_id int NOT NULL,
+		problem_id int NOT NULL,
+		score int NOT NULL DEFAULT 0,
+		attempts int NOT NULL DEFAULT 0,
+		solved_submission_id bigint NOT NULL DEFAULT 0,
+		solved_time bigint NOT NULL DEFAULT 0,
+		PRIMARY KEY (contest_id, user_id, problem_id),
+		FOREIGN KEY(contest_id) REFERENCES contests(id) ON DELETE CASCADE,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE TABLE IF NOT EXISTS contests_staff (
+		contest_id int NOT NULL,
+		user_id int NOT NULL,
+		role varchar(20) NOT NULL,
 		PRIMARY KEY (contest_id, user_id),
 		FOREIGN KEY(contest_id) REFERENCES contests(id) ON DELETE CASCADE,
 		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -80,7 +101,7 @@ func (c *ContestsUsersRepoImp) ListUsersByScore(ctx context.Context, contestID i
 	args = append(args, contestID)
 
 	stmt := `
-  	SELECT user_id, users.username FROM contests_users JOIN users ON contests_users.user_id = users.id WHERE contest_id = $1 ORDER BY score
+  	SELECT user_id, users.username FROM contests_users JOIN users ON contests_users.user_id = users.id WHERE contest_id = $1 AND start_offset = 0 ORDER BY score
   `
 
 	if limit != 0 {
@@ -113,7 +134,7 @@ func (c *ContestsUsersRepoImp) ListUsersByScore(ctx context.Context, contestID i
 
 func (c *ContestsUsersRepoImp) GetContestUsersCount(ctx context.Context, contestID int64) (int, error) {
 	stmt := `
-  	SELECT count(*) FROM contests_users WHERE contest_id = $
+  	SELECT count(*) FROM contests_users WHERE contest_id = $ AND start_offset = 0
   	`
 
 	var ans int
@@ -129,9 +150,150 @@ func (c *ContestsUsersRepoImp) AddUserScore(ctx context.Context, userID, contest
 	stmt := `
   		UPDATE contests_users SET score = score + $ WHERE contest_id = $ AND user_id = $
   	`
-	_, err := c.conn.ExecContext(ctx, stmt, delta, contestID, userID)
+	_, err := conn(ctx, c.conn).ExecContext(ctx, stmt, delta, contestID, userID)
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = pkg.ErrNotFound
 	}
 	return err
 }
+
+// UpdateStanding replaces the state of the problem for the user in the contest
+func (c *ContestsUsersRepoImp) UpdateStanding(ctx context.Context, standing structs.ContestStanding) error {
+	stmt := `
+	INSERT INTO contest_standings(contest_id, user_id, problem_id, score, attempts, solved_submission_id, solved_time)
+	VALUES($, $, $, $, $, $, $)
+	ON CONFLICT (contest_id, user_id, problem_id) DO UPDATE SET
+		score = EXCLUDED.score, attempts = EXCLUDED.attempts,
+		solved_submission_id = EXCLUDED.solved_submission_id, solved_time = EXCLUDED.solved_time
+	`
+	_, err := conn(ctx, c.conn).ExecContext(ctx, stmt, standing.ContestID, standing.UserID, standing.ProblemID, standing.Score, standing.Attempts, standing.SolvedSubmissionID, standing.SolvedTime)
+	return errors.WithStack(err)
+}
+
+// GetStandings gives the standings of all users of the contest, a user without any judged submission
+// has a single row with zero problem id
+func (c *ContestsUsersRepoImp) GetStandings(ctx context.Context, contestID int64) ([]structs.ContestStanding, error) {
+	stmt := `
+	SELECT contests_users.user_id, users.username, coalesce(contest_standings.problem_id, 0), coalesce(contest_standings.score, 0),
+		coalesce(contest_standings.attempts, 0), coalesce(contest_standings.solved_submission_id, 0), coalesce(contest_standings.solved_time, 0)
+	FROM contests_users JOIN users ON contests_users.user_id = users.id
+	LEFT JOIN contest_standings ON contest_standings.contest_id = contests_users.contest_id AND contest_standings.user_id = contests_users.user_id
+	WHERE contests_users.contest_id = $ AND contests_users.start_offset = 0 ORDER BY contests_users.user_id
+	`
+	rows, err := c.conn.QueryContext(ctx, stmt, contestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "coudn't run query stmt")
+	}
+	defer rows.Close()
+
+	ans := make([]structs.ContestStanding, 0)
+	for rows.Next() {
+		standing := structs.ContestStanding{ContestID: contestID}
+		err = rows.Scan(&standing.UserID, &standing.Username, &standing.ProblemID, &standing.Score,
+			&standing.Attempts, &standing.SolvedSubmissionID, &standing.SolvedTime)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on scan")
+		}
+		ans = append(ans, standing)
+	}
+	return ans, nil
+}
+
+// AddVirtual registers the user for a virtual participation which starts startOffset seconds after the contest
+func (c *ContestsUsersRepoImp) AddVirtual(ctx context.Context, contestID, userID, startOffset int64) error {
+	stmt := `
+	INSERT INTO contests_users(contest_id, user_id, start_offset) VALUES($, $, $)
+	`
+	_, err := c.conn.ExecContext(ctx, stmt, contestID, userID, startOffset)
+	return errors.WithStack(err)
+}
+
+// GetStartOffset gives the start offset of the user in the contest, it is zero if the user is not a virtual participant
+func (c *ContestsUsersRepoImp) GetStartOffset(ctx context.Context, contestID, userID int64) (int64, error) {
+	stmt := `
+	SELECT start_offset FROM contests_users WHERE contest_id = $ AND user_id = $
+	`
+	var ans int64
+	err := c.conn.QueryRowContext(ctx, stmt, contestID, userID).Scan(&ans)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, pkg.ErrNotFound
+	}
+	return ans, errors.WithStack(err)
+}
+
+// ListParticipants gives both the users of the contest and its virtual participants
+func (c *ContestsUsersRepoImp) ListParticipants(ctx context.Context, contestID int64) ([]structs.ContestParticipant, error) {
+	stmt := `
+	SELECT user_id, users.username, start_offset FROM contests_users JOIN users ON contests_users.user_id = users.id
+	WHERE contest_id = $ ORDER BY user_id
+	`
+	rows, err := c.conn.QueryContext(ctx, stmt, contestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "coudn't run query stmt")
+	}
+	defer rows.Close()
+
+	ans := make([]structs.ContestParticipant, 0)
+	for rows.Next() {
+		var participant structs.ContestParticipant
+		err = rows.Scan(&participant.UserID, &participant.Username, &participant.StartOffset)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on scan")
+		}
+		ans = append(ans, participant)
+	}
+	return ans, nil
+}
+
+// AddStaff gives the role in the contest to the user, the previous role of the user is replaced
+func (c *ContestsUsersRepoImp) AddStaff(ctx context.Context, contestID, userID int64, role string) error {
+	stmt := `
+	INSERT INTO contests_staff(contest_id, user_id, role) VALUES($, $, $)
+	ON CONFLICT (contest_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	_, err := c.conn.ExecContext(ctx, stmt, contestID, userID, role)
+	return errors.WithStack(err)
+}
+
+func (c *ContestsUsersRepoImp) DeleteStaff(ctx context.Context, contestID, userID int64) error {
+	stmt := `
+	DELETE FROM contests_staff WHERE contest_id = $ AND user_id = $
+	`
+	_, err := c.conn.ExecContext(ctx, stmt, contestID, userID)
+	return errors.WithStack(err)
+}
+
+// GetStaffRole gives the role of the user in the contest, it is empty if the user is not in the staff
+func (c *ContestsUsersRepoImp) GetStaffRole(ctx context.Context, contestID, userID int64) (string, error) {
+	stmt := `
+	SELECT role FROM contests_staff WHERE contest_id = $ AND user_id = $
+	`
+	var role string
+	err := c.conn.QueryRowContext(ctx, stmt, contestID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, errors.WithStack(err)
+}
+
+func (c *ContestsUsersRepoImp) ListStaff(ctx context.Context, contestID int64) ([]structs.ContestStaff, error) {
+	stmt := `
+	SELECT user_id, users.username, role FROM contests_staff JOIN users ON contests_staff.user_id = users.id
+	WHERE contest_id = $ ORDER BY user_id
+	`
+	rows, err := c.conn.QueryContext(ctx, stmt, contestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "coudn't run query stmt")
+	}
+
+	ans := make([]structs.ContestStaff, 0)
+	for rows.Next() {
+		var staff structs.ContestStaff
+		err = rows.Scan(&staff.UserID, &staff.Username, &staff.Role)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on scan")
+		}
+		ans = append(ans, staff)
+	}
+	return ans, nil
+}
@@ -38,8 +38,22 @@ func (a *ProblemsMetadataRepoImp) Migrate(ctx context.Context) (err error) {
 		hardness int DEFAULT NULL,
 	    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	is_private BOOL NOT NULL DEFAULT FALSE,
+	time_limit int NOT NULL DEFAULT 1000,
+	memory_limit int NOT NULL DEFAULT 256,
+	checker_type varchar(20) NOT NULL DEFAULT 'exact',
+	checker_epsilon REAL NOT NULL DEFAULT 0,
+	checker_language varchar(20) NOT NULL DEFAULT '',
+	checker_code TEXT NOT NULL DEFAULT '',
 	   FOREIGN KEY(created_by) REFERENCES users(id)
 	)
+	`, `
+	CREATE TABLE IF NOT EXISTS problems_access(
+	    problem_id int NOT NULL,
+	    user_id int NOT NULL,
+	    PRIMARY KEY (problem_id, user_id),
+	    FOREIGN KEY(problem_id) REFERENCES problems(id) ON DELETE CASCADE,
+	    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	)
 	`}
 	for _, s := range stmt {
 		_, err = a.conn.ExecContext(ctx, s)
@@ -55,21 +69,21 @@ func (a *ProblemsMetadataRepoImp) InsertProblem(ctx context.Context, problem str
 
 	stmt := `
 	INSERT INTO problems(
-		created_by, title, document_id, hardness, is_private) 
-		VALUES($, $, $, $, $) RETURNING id
+		created_by, title, document_id, hardness, is_private, time_limit, memory_limit) 
+		VALUES($, $, $, $, $, $, $) RETURNING id
 	`
 	var id int64
-	err := a.conn.QueryRowContext(ctx, stmt, problem.CreatedBy, problem.Title, problem.DocumentID, problem.Hardness, problem.IsPrivate).Scan(&id)
+	err := a.conn.QueryRowContext(ctx, stmt, problem.CreatedBy, problem.Title, problem.DocumentID, problem.Hardness, problem.IsPrivate, problem.TimeLimit, problem.MemoryLimit).Scan(&id)
 	return id, err
 }
 
 func (a *ProblemsMetadataRepoImp) GetProblem(ctx context.Context, id int64) (structs.Problem, error) {
 	stmt := `
-	SELECT created_by, title, document_id, solve_count, coalesce(hardness, -1) FROM problems WHERE id = $
+	SELECT created_by, title, document_id, solve_count, coalesce(hardness, -1), time_limit, memory_limit, checker_type, is_private FROM problems WHERE id = $
 	`
 	var problem structs.Problem
 	err := a.conn.QueryRowContext(ctx, stmt, id).Scan(
-		&problem.CreatedBy, &problem.Title, &problem.DocumentID, &problem.SolvedCount, &problem.Hardness)
+		&problem.CreatedBy, &problem.Title, &problem.DocumentID, &problem.SolvedCount, &problem.Hardness, &problem.TimeLimit, &problem.MemoryLimit, &problem.CheckerType, &problem.IsPrivate)
 	if errors.Is(err, sql.ErrNoRows) {
 		err = pkg.ErrNotFound
 	}
@@ -88,14 +102,16 @@ func (a *ProblemsMetadataRepoImp) GetProblemTitle(ctx context.Context, id int64)
 	return ans, err
 }
 
-func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn string, descending bool, limit, offset int, getCount bool) ([]structs.Problem, int, error) {
+func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn string, descending bool, limit, offset int, userID int64, getCount bool) ([]structs.Problem, int, error) {
 	stmt := `
 	SELECT id, created_by, title, document_id, solve_count, COALESCE(hardness, -1)
 	`
 	if getCount {
 		stmt = fmt.Sprintf("%s, COUNT(*) OVER() AS total_count", stmt)
 	}
-	stmt = fmt.Sprintf("%s FROM problems WHERE is_private = false ORDER BY", stmt)
+	// private problems are only listed for their owner and the users they are shared with
+	stmt = fmt.Sprintf(`%s FROM problems WHERE (is_private = false OR created_by = $
+		OR id IN (SELECT problem_id FROM problems_access WHERE user_id = $)) ORDER BY`, stmt)
 
 	colName, exists := SearchableColumns[searchColumn]
 	if !exists {
@@ -113,7 +129,7 @@ func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn
 		stmt = fmt.Sprintf("%s OFFSET %d", stmt, offset)
 	}
 
-	rows, err := a.conn.QueryContext(ctx, stmt)
+	rows, err := a.conn.QueryContext(ctx, stmt, userID, userID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -137,7 +153,7 @@ func (a *ProblemsMetadataRepoImp) ListProblems(ctx context.Context, searchColumn
 }
 
 // TODO: suitable query builder yada yada
-func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, title string, hardness int64) error {
+func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, title string, hardness, timeLimit, memoryLimit int64) error {
 	stmt := `
 	UPDATE problems SET
 	`
@@ -155,6 +171,20 @@ func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, t
 		args = append(args, hardness)
 		stmt += " hardness = $"
 	}
+	if timeLimit != 0 {
+		if len(args) > 0 {
+			stmt += ","
+		}
+		args = append(args, timeLimit)
+		stmt += " time_limit = $"
+	}
+	if memoryLimit != 0 {
+		if len(args) > 0 {
+			stmt += ","
+		}
+		args = append(args, memoryLimit)
+		stmt += " memory_limit = $"
+	}
 	if len(args) == 0 {
 		return nil
 	}
@@ -169,6 +199,26 @@ func (a *ProblemsMetadataRepoImp) UpdateProblem(ctx context.Context, id int64, t
 	return err
 }
 
+func (a *ProblemsMetadataRepoImp) GetChecker(ctx context.Context, id int64) (structs.Checker, error) {
+	stmt := `
+	SELECT checker_type, checker_epsilon, checker_language, checker_code FROM problems WHERE id = $
+	`
+	var checker structs.Checker
+	err := a.conn.QueryRowContext(ctx, stmt, id).Scan(&checker.Type, &checker.Epsilon, &checker.Language, &checker.Code)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = pkg.ErrNotFound
+	}
+	return checker, err
+}
+
+func (a *ProblemsMetadataRepoImp) UpdateChecker(ctx context.Context, id int64, checker structs.Checker) error {
+	stmt := `
+	UPDATE problems SET checker_type = $, checker_epsilon = $, checker_language = $, checker_code = $ WHERE id = $
+	`
+	_, err := a.conn.ExecContext(ctx, stmt, checker.Type, checker.Epsilon, checker.Language, checker.Code, id)
+	return err
+}
+
 func (a *ProblemsMetadataRepoImp) DeleteProblem(ctx context.Context, id int64) (string, error) {
 	stmt := `
 	DELETE FROM problems WHERE id = $ RETURNING document_id
@@ -180,13 +230,62 @@ func (a *ProblemsMetadataRepoImp) DeleteProblem(ctx context.Context, id int64) (
 	}
 	return documentId, err
 }
-func (a *ProblemsMetadataRepoImp) AddSolve(ctx context.Context, id int64) error {
+func (a *ProblemsMetadataRepoImp) AddSolve(ctx context.Context, id int64, delta int) error {
 	stmt := `
-	UPDATE problems SET solve_count = solve_count + 1 WHERE id = $
+	UPDATE problems SET solve_count = solve_count + $ WHERE id = $
 	`
-	_, err := a.conn.ExecContext(ctx, stmt, id)
+	_, err := conn(ctx, a.conn).ExecContext(ctx, stmt, delta, id)
 	if errors.Is(err, pgx.ErrNoRows) {
 		err = pkg.ErrNotFound
 	}
 	return err
 }
+
+// GrantAccess lets the user see the problem when it is private
+func (a *ProblemsMetadataRepoImp) GrantAccess(ctx context.Context, id, userID int64) error {
+	stmt := `
+	INSERT INTO problems_access(problem_id, user_id) VALUES($, $) ON CONFLICT DO NOTHING
+	`
+	_, err := a.conn.ExecContext(ctx, stmt, id, userID)
+	return err
+}
+
+func (a *ProblemsMetadataRepoImp) RevokeAccess(ctx context.Context, id, userID int64) error {
+	stmt := `
+	DELETE FROM problems_access WHERE problem_id = $ AND user_id = $
+	`
+	_, err := a.conn.ExecContext(ctx, stmt, id, userID)
+	return err
+}
+
+func (a *ProblemsMetadataRepoImp) HasAccess(ctx context.Context, id, userID int64) (bool, error) {
+	stmt := `
+	SELECT EXISTS(SELECT 1 FROM problems_access WHERE problem_id = $ AND user_id = $)
+	`
+	var ans bool
+	err := a.conn.QueryRowContext(ctx, stmt, id, userID).Scan(&ans)
+	return ans, err
+}
+
+// ListAccess gives the users who the problem is shared with
+func (a *ProblemsMetadataRepoImp) ListAccess(ctx context.Context, id int64) ([]structs.ProblemAccess, error) {
+	stmt := `
+	SELECT users.id, users.username FROM problems_access JOIN users ON problems_access.user_id = users.id
+	WHERE problem_id = $ ORDER BY users.id
+	`
+	rows, err := a.conn.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ans := make([]structs.ProblemAccess, 0)
+	for rows.Next() {
+		var access structs.ProblemAccess
+		if err := rows.Scan(&access.UserID, &access.Username); err != nil {
+			return nil, err
+		}
+		ans = append(ans, access)
+	}
+	return ans, nil
+}
@@ -24,7 +24,7 @@ func NewSubmissionRepo(ctx context.Context, conn *sql.DB) (repos.SubmissionMetad
 
 func (a *SubmissionRepoImp) Migrate(ctx context.Context) error {
 	stmts := []string{
-		"CREATE TYPE submission_status AS ENUM('unprocessed', 'processing', 'processed')",
+		"CREATE TYPE submission_status AS ENUM('unprocessed', 'processing', 'processed', 'queued', 'compiling', 'running', 'judge_error')",
 		"CREATE TYPE submission_language AS ENUM('python')",
 		`
 		CREATE TABLE IF NOT EXISTS submissions(
@@ -39,11 +39,28 @@ func (a *SubmissionRepoImp) Migrate(ctx context.Context) error {
 			is_final boolean DEFAULT FALSE,
 			public boolean DEFAULT FALSE,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			status_message text NOT NULL DEFAULT '',
+			tests_done int NOT NULL DEFAULT 0,
+			tests_total int NOT NULL DEFAULT 0,
+			rejudge_id bigint,
+			pending_result_id varchar(70) NOT NULL DEFAULT '',
 
 			unique(id),
 			primary key (id, problem_id, user_id),
 
 			CONSTRAINT fk_problem_id FOREIGN KEY(problem_id) REFERENCES problems(id),
+			CONSTRAINT fk_user_id FOREIGN KEY(user_id) REFERENCES users(id)
+	)`,
+		`
+		CREATE TABLE IF NOT EXISTS rejudges(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id bigint NOT NULL,
+			problem_id bigint NOT NULL DEFAULT 0,
+			contest_id bigint NOT NULL DEFAULT 0,
+			submission_id bigint NOT NULL DEFAULT 0,
+			total int NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
 			CONSTRAINT fk_user_id FOREIGN KEY(user_id) REFERENCES users(id)
 	)`}
 
@@ -70,12 +87,12 @@ func (s *SubmissionRepoImp) Insert(ctx context.Context, submission structs.Submi
 
 func (s *SubmissionRepoImp) Get(ctx context.Context, id int64) (structs.SubmissionMetadata, error) {
 	stmt := `
-	SELECT id, problem_id, user_id, file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at FROM submissions WHERE id = $
+	SELECT id, problem_id, user_id, file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at, status_message, tests_done, tests_total FROM submissions WHERE id = $
 	`
 	var ans structs.SubmissionMetadata
 	var t time.Time
-	err := s.conn.QueryRowContext(ctx, stmt, id).Scan(
-		&ans.ID, &ans.ProblemID, &ans.UserID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+	err := conn(ctx, s.conn).QueryRowContext(ctx, stmt, id).Scan(
+		&ans.ID, &ans.ProblemID, &ans.UserID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t, &ans.StatusMessage, &ans.TestsDone, &ans.TestsTotal)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		err = pkg.ErrNotFound
@@ -130,7 +147,7 @@ func (s *SubmissionRepoImp) GetFinalSubmission(ctx context.Context, problemID, u
 
 	var ans structs.SubmissionMetadata
 	var t time.Time
-	err := s.conn.QueryRowContext(ctx, stmt, userID, problemID, contestID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+	err := conn(ctx, s.conn).QueryRowContext(ctx, stmt, userID, problemID, contestID).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
 	ans.CreatedAT = t.Format(time.RFC3339)
 	if errors.Is(err, sql.ErrNoRows) {
 		return ans, pkg.ErrNotFound
@@ -152,9 +169,9 @@ func (s *SubmissionRepoImp) UpdateJudgeResults(ctx context.Context, problemID, u
 	var err error
 	if isFinal {
 		if contestID != 0 {
-			_, err = s.conn.ExecContext(ctx, stmt, problemID, userID, contestID)
+			_, err = conn(ctx, s.conn).ExecContext(ctx, stmt, problemID, userID, contestID)
 		} else {
-			_, err = s.conn.ExecContext(ctx, stmt, problemID, userID)
+			_, err = conn(ctx, s.conn).ExecContext(ctx, stmt, problemID, userID)
 		}
 		if err != nil {
 			return err
@@ -164,10 +181,154 @@ func (s *SubmissionRepoImp) UpdateJudgeResults(ctx context.Context, problemID, u
 	stmt = `
 	UPDATE submissions SET status = 'processed', score = $, judge_result_id = $, is_final = $ WHERE id = $
 	`
-	_, err = s.conn.ExecContext(ctx, stmt, score, docID, isFinal, submissionID)
+	_, err = conn(ctx, s.conn).ExecContext(ctx, stmt, score, docID, isFinal, submissionID)
+	return err
+}
+
+// UpdateStatus records the progress of judging, a judged submission or one with a judge error is never changed by it.
+// since progress is reported asynchronously, this stops late updates from overwriting the final status
+func (s *SubmissionRepoImp) UpdateStatus(ctx context.Context, submissionID int64, status, message string, testsDone, testsTotal int) error {
+	stmt := `
+	UPDATE submissions SET status = $, status_message = $, tests_done = $, tests_total = $
+	WHERE id = $ AND status NOT IN ('processed', 'judge_error')
+	`
+	_, err := s.conn.ExecContext(ctx, stmt, status, message, testsDone, testsTotal, submissionID)
 	return err
 }
 
+func (s *SubmissionRepoImp) SetPendingResult(ctx context.Context, submissionID int64, judgeResultID string) error {
+	stmt := `
+	UPDATE submissions SET pending_result_id = $ WHERE id = $
+	`
+	_, err := conn(ctx, s.conn).ExecContext(ctx, stmt, judgeResultID, submissionID)
+	return err
+}
+
+func (s *SubmissionRepoImp) GetPendingResult(ctx context.Context, submissionID int64) (string, error) {
+	stmt := `
+	SELECT pending_result_id FROM submissions WHERE id = $
+	`
+	var ans string
+	err := conn(ctx, s.conn).QueryRowContext(ctx, stmt, submissionID).Scan(&ans)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", pkg.ErrNotFound
+	}
+	return ans, err
+}
+
+// UpdateFinalSubmission marks the submission with the highest score as final, the last one is taken if there are more.
+// the final submission is returned
+func (s *SubmissionRepoImp) UpdateFinalSubmission(ctx context.Context, problemID, userID, contestID int64) (structs.SubmissionMetadata, error) {
+	args := []interface{}{problemID, userID}
+	cond := "problem_id = $ AND user_id = $"
+	if contestID != 0 {
+		args = append(args, contestID)
+		cond += " AND contest_id = $"
+	}
+
+	var ans structs.SubmissionMetadata
+	err := inTx(ctx, s.conn, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE submissions SET is_final = false WHERE "+cond, args...)
+		if err != nil {
+			return errors.Wrap(err, "error on clearing final submissions")
+		}
+
+		stmt := fmt.Sprintf(`
+		UPDATE submissions SET is_final = true WHERE id = (
+			SELECT id FROM submissions WHERE %s ORDER BY score DESC, id DESC LIMIT 1
+		) RETURNING id, problem_id, user_id, file_name, score, coalesce(judge_result_id, ''), status, language, is_final, public, created_at
+		`, cond)
+		var t time.Time
+		err = tx.QueryRowContext(ctx, stmt, args...).Scan(&ans.ID, &ans.ProblemID, &ans.UserID, &ans.FileName, &ans.Score, &ans.JudgeResultID, &ans.Status, &ans.Language, &ans.IsFinal, &ans.Public, &t)
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkg.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "error on setting final submission")
+		}
+		ans.CreatedAT = t.Format(time.RFC3339)
+		return nil
+	})
+	return ans, err
+}
+
+// InsertRejudge creates the rejudge job and resets the judged submissions it covers, so they can be dispatched again.
+// ids of the reset submissions are returned, submissions which are being judged are left as they are
+func (s *SubmissionRepoImp) InsertRejudge(ctx context.Context, rejudge structs.Rejudge) (int64, []int64, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on starting transaction")
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+	INSERT INTO rejudges(user_id, problem_id, contest_id, submission_id) VALUES($, $, $, $) RETURNING id
+	`, rejudge.UserID, rejudge.ProblemID, rejudge.ContestID, rejudge.SubmissionID).Scan(&id)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on inserting rejudge")
+	}
+
+	args := []interface{}{id}
+	var cond string
+	switch {
+	case rejudge.SubmissionID != 0:
+		args = append(args, rejudge.SubmissionID)
+		cond = "id = $"
+	case rejudge.ContestID != 0:
+		args = append(args, rejudge.ContestID)
+		cond = "contest_id = $"
+	default:
+		args = append(args, rejudge.ProblemID)
+		cond = "problem_id = $"
+	}
+	stmt := fmt.Sprintf(`
+	UPDATE submissions SET status = 'unprocessed', status_message = '', tests_done = 0, judge_result_id = '', pending_result_id = '', rejudge_id = $
+	WHERE %s AND status IN ('processed', 'judge_error') RETURNING id
+	`, cond)
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on resetting submissions")
+	}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var submissionID int64
+		if err := rows.Scan(&submissionID); err != nil {
+			rows.Close()
+			return 0, nil, errors.Wrap(err, "error on reading row")
+		}
+		ids = append(ids, submissionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, errors.Wrap(err, "error on resetting submissions")
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE rejudges SET total = $ WHERE id = $`, len(ids), id)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error on updating total of rejudge")
+	}
+	return id, ids, errors.WithStack(tx.Commit())
+}
+
+func (s *SubmissionRepoImp) GetRejudge(ctx context.Context, id int64) (structs.Rejudge, error) {
+	stmt := `
+	SELECT rejudges.id, rejudges.user_id, rejudges.problem_id, rejudges.contest_id, rejudges.submission_id, rejudges.total, rejudges.created_at,
+		COUNT(submissions.id) FILTER (WHERE submissions.status = 'processed'),
+		COUNT(submissions.id) FILTER (WHERE submissions.status = 'judge_error')
+	FROM rejudges LEFT JOIN submissions ON submissions.rejudge_id = rejudges.id
+	WHERE rejudges.id = $ GROUP BY rejudges.id
+	`
+	var ans structs.Rejudge
+	var t time.Time
+	err := s.conn.QueryRowContext(ctx, stmt, id).Scan(&ans.ID, &ans.UserID, &ans.ProblemID, &ans.ContestID, &ans.SubmissionID, &ans.Total, &t, &ans.Judged, &ans.Failed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ans, pkg.ErrNotFound
+	}
+	ans.CreatedAt = t.Format(time.RFC3339)
+	return ans, err
+}
+
 func (s *SubmissionRepoImp) ListSubmissions(ctx context.Context, problemID, userID, contestID int64, descending bool, limit, offset int, getCount bool) ([]structs.SubmissionMetadata, int, error) {
 	args := make([]interface{}, 0)
 
@@ -220,7 +381,7 @@ func (s *SubmissionRepoImp) ListSubmissions(ctx context.Context, problemID, user
 		stmt += " OFFSET $"
 	}
 
-	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	rows, err := conn(ctx, s.conn).QueryContext(ctx, stmt, args...)
 
 	if err != nil {
 		return nil, 0, err
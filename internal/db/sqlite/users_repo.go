@@ -27,6 +27,7 @@ func (a *UsersRepoImp) Migrate(ctx context.Context) error {
 	    email varchar(40),
 	    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	    is_verified boolean DEFAULT false,
+	    role varchar(20) NOT NULL DEFAULT 'contestant',
 	    UNIQUE (username),
 	    UNIQUE (email)
 	)
@@ -54,19 +55,19 @@ func (a *UsersRepoImp) VerifyUser(ctx context.Context, userID int64) error {
 
 func (a *UsersRepoImp) GetByUsername(ctx context.Context, username string) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE username = $ 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE username = $ 
 	`
 	var user structs.User
-	err := a.conn.QueryRowContext(ctx, stmt, username).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRowContext(ctx, stmt, username).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
 func (a *UsersRepoImp) GetByID(ctx context.Context, userID int64) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE id = $ 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE id = $ 
 	`
 	var user structs.User
-	err := a.conn.QueryRowContext(ctx, stmt, userID).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRowContext(ctx, stmt, userID).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
@@ -81,10 +82,10 @@ func (a *UsersRepoImp) GetUsername(ctx context.Context, userID int64) (string, e
 
 func (a *UsersRepoImp) GetByEmail(ctx context.Context, email string) (structs.User, error) {
 	stmt := `
-	SELECT id, username, password, email, is_verified FROM users WHERE email = $ 
+	SELECT id, username, password, email, is_verified, role FROM users WHERE email = $ 
 	`
 	var user structs.User
-	err := a.conn.QueryRowContext(ctx, stmt, email).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified)
+	err := a.conn.QueryRowContext(ctx, stmt, email).Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role)
 	return user, err
 }
 
@@ -103,13 +104,16 @@ func (a *UsersRepoImp) UpdateUser(ctx context.Context, user structs.User) error
 	}
 	if user.Email != "" {
 		args = append(args, user.Email)
-		if len(args) > 1 {
+		if len(args) > 2 {
 			stmt += ","
 		}
 		stmt += fmt.Sprintf("email = $%d", len(args))
 	}
 	if user.EncryptedPassword != "" {
 		args = append(args, user.EncryptedPassword)
+		if len(args) > 2 {
+			stmt += ","
+		}
 		stmt += fmt.Sprintf("password = $%d", len(args))
 	}
 	stmt += " WHERE id = $"
@@ -120,3 +124,42 @@ func (a *UsersRepoImp) UpdateUser(ctx context.Context, user structs.User) error
 	_, err := a.conn.ExecContext(ctx, stmt, args...)
 	return err
 }
+
+// SetRole changes the role of the user, it is one of structs.Roles
+func (a *UsersRepoImp) SetRole(ctx context.Context, userID int64, role string) error {
+	stmt := `
+	UPDATE users SET role = $ WHERE id = $
+	`
+	_, err := a.conn.ExecContext(ctx, stmt, role, userID)
+	return err
+}
+
+// UpdatePassword replaces the stored password of the user, it should be hashed before
+func (a *UsersRepoImp) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	stmt := `
+	UPDATE users SET password = $ WHERE id = $
+	`
+	_, err := a.conn.ExecContext(ctx, stmt, password, userID)
+	return err
+}
+
+func (a *UsersRepoImp) ListUsers(ctx context.Context) ([]structs.User, error) {
+	stmt := `
+	SELECT id, username, password, email, is_verified, role FROM users ORDER BY id
+	`
+	rows, err := a.conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ans := make([]structs.User, 0)
+	for rows.Next() {
+		var user structs.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.EncryptedPassword, &user.Email, &user.Verified, &user.Role); err != nil {
+			return nil, err
+		}
+		ans = append(ans, user)
+	}
+	return ans, nil
+}
@@ -0,0 +1,58 @@
+package judge
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ocontest/backend/pkg/structs"
+)
+
+// watcherBuffer is the number of events kept for a slow watcher, later events are dropped until it reads them
+const watcherBuffer = 64
+
+// progressHub sends the progress reports of submissions to the clients watching them on this backend
+type progressHub struct {
+	mu       sync.Mutex
+	watchers map[int64]map[chan structs.JudgeProgress]struct{}
+}
+
+func newProgressHub() *progressHub {
+	return &progressHub{
+		watchers: make(map[int64]map[chan structs.JudgeProgress]struct{}),
+	}
+}
+
+// watch returns a channel of the reports of the submission, it is closed when ctx is done
+func (h *progressHub) watch(ctx context.Context, submissionID int64) <-chan structs.JudgeProgress {
+	ch := make(chan structs.JudgeProgress, watcherBuffer)
+
+	h.mu.Lock()
+	if h.watchers[submissionID] == nil {
+		h.watchers[submissionID] = make(map[chan structs.JudgeProgress]struct{})
+	}
+	h.watchers[submissionID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.watchers[submissionID], ch)
+		if len(h.watchers[submissionID]) == 0 {
+			delete(h.watchers, submissionID)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func (h *progressHub) publish(progress structs.JudgeProgress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.watchers[progress.SubmissionID] {
+		select {
+		case ch <- progress:
+		default:
+		}
+	}
+}
@@ -2,6 +2,9 @@ package judge
 
 import (
 	"context"
+	"encoding/json"
+	"log"
+	"time"
 
 	"github.com/ocontest/backend/internal/db/repos"
 	"github.com/ocontest/backend/internal/minio"
@@ -9,11 +12,18 @@ import (
 	"github.com/ocontest/backend/pkg/configs"
 	"github.com/ocontest/backend/pkg/structs"
 
+	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
 )
 
+const resultFetchTimeout = time.Minute
+
 type Judge interface {
-	Dispatch(ctx context.Context, submissionID, contestID int64) (err error)
+	Dispatch(ctx context.Context, submissionID int64) (err error)
+	ProcessResult(ctx context.Context, resp structs.JudgeResponse) error
+	ProcessProgress(ctx context.Context, progress structs.JudgeProgress) error
+	StartResultProcessor()
+	Watch(ctx context.Context, submissionID int64) <-chan structs.JudgeProgress
 	GetTestResults(ctx context.Context, id string) (structs.JudgeResponse, error)
 	GetScore(ctx context.Context, id string) (int, error)
 }
@@ -21,15 +31,19 @@ type Judge interface {
 type JudgeImp struct {
 	queue                  JudgeQueue
 	contestUsersRepo       repos.ContestsUsersRepo
+	contestsRepo           repos.ContestsMetadataRepo
 	problemsRepo           repos.ProblemsMetadataRepo
 	submissionMetadataRepo repos.SubmissionMetadataRepo
 	minioHandler           minio.MinioHandler
 	testcaseRepo           repos.TestCaseRepo
 	judgeRepo              repos.JudgeRepo
+	transactor             repos.Transactor
+	hub                    *progressHub
 }
 
 func NewJudge(c configs.SectionJudge, submissionMetadataRepo repos.SubmissionMetadataRepo,
-	minioHandler minio.MinioHandler, testcaseRepo repos.TestCaseRepo, contestUsersRepo repos.ContestsUsersRepo, judgeRepo repos.JudgeRepo, problemsRepo repos.ProblemsMetadataRepo) (Judge, error) {
+	minioHandler minio.MinioHandler, testcaseRepo repos.TestCaseRepo, contestUsersRepo repos.ContestsUsersRepo, judgeRepo repos.JudgeRepo, problemsRepo repos.ProblemsMetadataRepo,
+	contestsRepo repos.ContestsMetadataRepo, transactor repos.Transactor) (Judge, error) {
 	queue, err := NewJudgeQueue(c.Nats)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create judge queue for judge")
@@ -43,10 +57,21 @@ func NewJudge(c configs.SectionJudge, submissionMetadataRepo repos.SubmissionMet
 		judgeRepo:              judgeRepo,
 		testcaseRepo:           testcaseRepo,
 		contestUsersRepo:       contestUsersRepo,
+		contestsRepo:           contestsRepo,
+		transactor:             transactor,
+		hub:                    newProgressHub(),
 	}, nil
 }
 
-func (j JudgeImp) Dispatch(ctx context.Context, submissionID, contestID int64) (err error) {
+// Dispatch puts the submission in the judge queue, the result is handled by ProcessResult when a runner publishes it
+func (j JudgeImp) Dispatch(ctx context.Context, submissionID int64) (err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		j.failSubmission(ctx, submissionID, "couldn't send the submission to judge")
+	}()
+
 	submission, err := j.submissionMetadataRepo.Get(ctx, submissionID)
 	if err != nil {
 		err = errors.Wrap(err, "couldn't get submission from db")
@@ -63,64 +88,318 @@ func (j JudgeImp) Dispatch(ctx context.Context, submissionID, contestID int64) (
 		err = errors.Wrap(err, "couldn't get test cases from db")
 		return
 	}
+	problem, err := j.problemsRepo.GetProblem(ctx, submission.ProblemID)
+	if err != nil {
+		err = errors.Wrap(err, "couldn't get problem from db")
+		return
+	}
+	checker, err := j.problemsRepo.GetChecker(ctx, submission.ProblemID)
+	if err != nil {
+		err = errors.Wrap(err, "couldn't get checker of problem from db")
+		return
+	}
 	req := structs.JudgeRequest{
 		SubmissionID: submissionID,
 		Code:         string(code),
+		Language:     submission.Language,
+		TimeLimit:    problem.TimeLimit,
+		MemoryLimit:  problem.MemoryLimit,
+		Checker:      checker,
 		Testcases:    testCases,
 	}
 
-	resp, err := j.queue.Send(req)
-	if err == nil && resp.ServerError != "" {
-		err = errors.New(resp.ServerError)
+	// status is updated before sending, otherwise it may overwrite progress reported by a fast runner
+	err = j.submissionMetadataRepo.UpdateStatus(ctx, submissionID, structs.SubmissionQueued, "", 0, len(testCases))
+	if err != nil {
+		err = errors.Wrap(err, "couldn't update status of submission")
+		return
 	}
 
+	err = j.queue.Send(req)
 	if err != nil {
 		err = errors.Wrap(err, "error on send to queue")
 		return
 	}
+	j.notify(structs.JudgeProgress{SubmissionID: submissionID, Status: structs.SubmissionQueued, TestsTotal: len(testCases)})
+	return
+}
 
-	docID, err := j.judgeRepo.Insert(ctx, resp)
+// StartResultProcessor consumes judge results and progress reports published by runners, a result is acked only after it is stored.
+// all of the reports are also given to the watchers of submissions on this backend
+func (j JudgeImp) StartResultProcessor() {
+	_, err := j.queue.WatchProgress(j.hub.publish)
 	if err != nil {
-		return errors.Wrap(err, "couldn't insert judge result to judge repos")
+		log.Fatal("couldn't watch judge progress: ", err)
 	}
 
-	currentScore := j.CalcScore(resp.TestResults)
-	lastSub, err := j.submissionMetadataRepo.GetFinalSubmission(ctx, submission.ProblemID, submission.UserID, contestID)
-	if err != nil && !errors.Is(err, pkg.ErrNotFound) {
-		return errors.Wrap(err, "coudn't get last submission")
+	_, err = j.queue.SubscribeProgress(func(progress structs.JudgeProgress) {
+		if err := j.ProcessProgress(context.Background(), progress); err != nil {
+			pkg.Log.Error("error on processing judge progress of submission ", progress.SubmissionID, ": ", err)
+		}
+	})
+	if err != nil {
+		log.Fatal("couldn't subscribe to judge progress: ", err)
 	}
 
-	isFinal := true
-	if lastSub.Score > currentScore {
-		isFinal = false
+	sub, err := j.queue.SubscribeResults()
+	if err != nil {
+		log.Fatal("couldn't subscribe to judge results: ", err)
 	}
 
-	err = j.submissionMetadataRepo.UpdateJudgeResults(ctx, submission.ProblemID, submission.UserID, submission.ContestID, submissionID, docID, currentScore, isFinal)
+	failures := 0
+	for {
+		msgs, err := sub.Fetch(1, nats.MaxWait(resultFetchTimeout))
+		if errors.Is(err, nats.ErrTimeout) {
+			failures = 0
+			continue
+		}
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			log.Fatal("connection of judge queue is closed: ", err)
+		}
+		if errors.Is(err, nats.ErrConsumerDeleted) {
+			pkg.Log.Error("consumer of judge results is deleted, subscribing again")
+			_ = sub.Unsubscribe()
+			if sub, err = j.queue.SubscribeResults(); err != nil {
+				log.Fatal("couldn't subscribe to judge results: ", err)
+			}
+			continue
+		}
+		if err != nil {
+			pkg.Log.Error("error on getting judge result from queue: ", err)
+			time.Sleep(FetchRetryDelay(failures))
+			failures++
+			continue
+		}
+		failures = 0
+
+		for _, msg := range msgs {
+			var resp structs.JudgeResponse
+			if err := json.Unmarshal(msg.Data, &resp); err != nil {
+				pkg.Log.Error("error on unmarshal judge result: ", err)
+				// the submission id is still set when only other fields are invalid
+				if resp.SubmissionID != 0 {
+					j.failSubmission(context.Background(), resp.SubmissionID, "invalid judge result")
+				}
+				msg.Term()
+				continue
+			}
+			if err := j.ProcessResult(context.Background(), resp); err != nil {
+				pkg.Log.Error("error on processing judge result of submission ", resp.SubmissionID, ": ", err)
+				if _, last := j.queue.Delivery(msg); last {
+					j.failSubmission(context.Background(), resp.SubmissionID, "couldn't store the judge result")
+					msg.Term()
+					continue
+				}
+				msg.Nak()
+				continue
+			}
+			if err := msg.Ack(); err != nil {
+				pkg.Log.Error("error on ack judge result: ", err)
+			}
+		}
+	}
+}
+
+// Watch gives the progress reports of a submission until ctx is done, reports which are sent before calling it are not included
+func (j JudgeImp) Watch(ctx context.Context, submissionID int64) <-chan structs.JudgeProgress {
+	return j.hub.watch(ctx, submissionID)
+}
+
+// failSubmission marks the submission as failed to judge with the message, failures are only logged
+func (j JudgeImp) failSubmission(ctx context.Context, submissionID int64, message string) {
+	err := j.submissionMetadataRepo.UpdateStatus(ctx, submissionID, structs.SubmissionJudgeError, message, 0, 0)
 	if err != nil {
-		return errors.Wrap(err, "couldn't update judge result in submission metadata repos")
+		pkg.Log.Error("error on updating status of submission ", submissionID, ": ", err)
+	}
+	j.notify(structs.JudgeProgress{SubmissionID: submissionID, Status: structs.SubmissionJudgeError, Message: message})
+}
+
+// notify publishes a report of the backend itself for watchers of the submission, failures are only logged
+func (j JudgeImp) notify(progress structs.JudgeProgress) {
+	if err := j.queue.PublishProgress(progress); err != nil {
+		pkg.Log.Warning("error on publishing progress of submission ", progress.SubmissionID, ": ", err)
 	}
-	if lastSub.Score != 100 && currentScore == 100 {
-		err = j.problemsRepo.AddSolve(ctx, submission.ProblemID)
+}
+
+func (j JudgeImp) ProcessProgress(ctx context.Context, progress structs.JudgeProgress) error {
+	if progress.Status != structs.SubmissionCompiling && progress.Status != structs.SubmissionRunning {
+		// other statuses are reported by the backend itself after storing them
+		return nil
+	}
+	return j.submissionMetadataRepo.UpdateStatus(ctx, progress.SubmissionID, progress.Status, "", progress.TestsDone, progress.TestsTotal)
+}
+
+// ProcessResult stores the result of a judged or rejudged submission and updates final submissions, solve counts and contest scores
+func (j JudgeImp) ProcessResult(ctx context.Context, resp structs.JudgeResponse) error {
+	submission, err := j.submissionMetadataRepo.Get(ctx, resp.SubmissionID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get submission from db")
+	}
+	if submission.JudgeResultID != "" {
+		// redelivered result which is already stored
+		return nil
+	}
+	if resp.ServerError != "" {
+		pkg.Log.Error("runner failed to judge submission ", resp.SubmissionID, ": ", resp.ServerError)
+		err = j.submissionMetadataRepo.UpdateStatus(ctx, resp.SubmissionID, structs.SubmissionJudgeError, resp.ServerError, 0, len(resp.TestResults))
 		if err != nil {
-			return errors.Wrap(err, "coudn't update solve count")
+			return err
 		}
+		j.notify(structs.JudgeProgress{
+			SubmissionID: resp.SubmissionID,
+			Status:       structs.SubmissionJudgeError,
+			TestsTotal:   len(resp.TestResults),
+			Message:      resp.ServerError,
+		})
+		return nil
+	}
+	contestID := submission.ContestID
+	submissionID := submission.ID
+
+	resp.Groups, err = j.testcaseRepo.GetGroups(ctx, submission.ProblemID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get test groups from db")
 	}
 
-	if isFinal && contestID != 0 {
-		err = j.contestUsersRepo.AddUserScore(ctx, submission.UserID, contestID, currentScore-lastSub.Score)
+	docID, err := j.storeResult(ctx, resp)
+	if err != nil {
+		return err
+	}
+
+	currentScore := j.CalcScore(resp.TestResults, resp.Groups)
+	// every update is in one transaction with setting judge_result_id, so a failed result is processed again
+	// from the start when it is redelivered, and a processed one is skipped
+	err = j.transactor.WithTx(ctx, func(ctx context.Context) error {
+		lastSub, err := j.submissionMetadataRepo.GetFinalSubmission(ctx, submission.ProblemID, submission.UserID, contestID)
+		if err != nil && !errors.Is(err, pkg.ErrNotFound) {
+			return errors.Wrap(err, "coudn't get last submission")
+		}
+
+		// the result is stored first and then the final submission is chosen again, since a rejudged submission
+		// may be the final one and lose its score
+		err = j.submissionMetadataRepo.UpdateJudgeResults(ctx, submission.ProblemID, submission.UserID, submission.ContestID, submissionID, docID, currentScore, false)
+		if err != nil {
+			return errors.Wrap(err, "couldn't update judge result in submission metadata repos")
+		}
+		finalSub, err := j.submissionMetadataRepo.UpdateFinalSubmission(ctx, submission.ProblemID, submission.UserID, contestID)
 		if err != nil {
-			return errors.Wrap(err, "couldn't update contest score")
+			return errors.Wrap(err, "couldn't update final submission")
+		}
+
+		solveDelta := 0
+		if lastSub.Score != 100 && finalSub.Score == 100 {
+			solveDelta = 1
+		} else if lastSub.Score == 100 && finalSub.Score != 100 {
+			solveDelta = -1
+		}
+		if solveDelta != 0 {
+			err = j.problemsRepo.AddSolve(ctx, submission.ProblemID, solveDelta)
+			if err != nil {
+				return errors.Wrap(err, "coudn't update solve count")
+			}
+		}
+
+		if contestID != 0 && finalSub.Score != lastSub.Score {
+			err = j.contestUsersRepo.AddUserScore(ctx, submission.UserID, contestID, finalSub.Score-lastSub.Score)
+			if err != nil {
+				return errors.Wrap(err, "couldn't update contest score")
+			}
 		}
+		if contestID != 0 {
+			err = j.updateStanding(ctx, submission)
+			if err != nil {
+				return errors.Wrap(err, "couldn't update contest standing")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	j.notify(structs.JudgeProgress{
+		SubmissionID: submissionID,
+		Status:       structs.SubmissionProcessed,
+		TestsDone:    len(resp.TestResults),
+		TestsTotal:   len(resp.TestResults),
+		Score:        currentScore,
+	})
 	return nil
 }
 
+// storeResult inserts the result in the judge repo once, a retry of processing it gives the id of the stored one
+func (j JudgeImp) storeResult(ctx context.Context, resp structs.JudgeResponse) (string, error) {
+	docID, err := j.submissionMetadataRepo.GetPendingResult(ctx, resp.SubmissionID)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't get pending judge result")
+	}
+	if docID != "" {
+		return docID, nil
+	}
+
+	docID, err = j.judgeRepo.Insert(ctx, resp)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't insert judge result to judge repos")
+	}
+	if err := j.submissionMetadataRepo.SetPendingResult(ctx, resp.SubmissionID, docID); err != nil {
+		return "", errors.Wrap(err, "couldn't set pending judge result")
+	}
+	return docID, nil
+}
+
+// updateStanding computes the state of the problem of the submission for its user in the contest again,
+// so the scoreboard doesn't have to read every submission
+func (j JudgeImp) updateStanding(ctx context.Context, submission structs.SubmissionMetadata) error {
+	submissions, _, err := j.submissionMetadataRepo.ListSubmissions(ctx, submission.ProblemID, submission.UserID, submission.ContestID, false, 0, 0, false)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get submissions of user")
+	}
+	contest, err := j.contestsRepo.GetContest(ctx, submission.ContestID)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get contest")
+	}
+	end := contest.StartTime + int64(contest.Duration)
+
+	standing := structs.ContestStanding{
+		ContestID: submission.ContestID,
+		UserID:    submission.UserID,
+		ProblemID: submission.ProblemID,
+	}
+	for _, s := range submissions {
+		if s.Status != structs.SubmissionProcessed || standing.SolvedSubmissionID != 0 {
+			continue
+		}
+		// standings are the official ranking, submissions of virtual participations are after the end
+		t, err := time.Parse(time.RFC3339, s.CreatedAT)
+		if err == nil && t.Unix() >= end {
+			continue
+		}
+		if s.Score > standing.Score {
+			standing.Score = s.Score
+		}
+		if s.Score < 100 {
+			standing.Attempts++
+			continue
+		}
+		standing.SolvedSubmissionID = s.ID
+		if err == nil {
+			standing.SolvedTime = t.Unix()
+		}
+	}
+	return j.contestUsersRepo.UpdateStanding(ctx, standing)
+}
+
 func (j JudgeImp) GetTestResults(ctx context.Context, id string) (structs.JudgeResponse, error) {
 	return j.judgeRepo.GetResults(ctx, id)
 }
 
-func (j JudgeImp) CalcScore(t []structs.TestResult) int {
+// CalcScore gives the score of a submission out of 100. without groups every test has the same weight,
+// otherwise the points of a group are given if all of its tests passed and tests out of groups are ignored
+func (j JudgeImp) CalcScore(t []structs.TestResult, groups []structs.TestGroup) int {
+	if len(groups) != 0 {
+		return calcGroupsScore(t, groups)
+	}
+
 	total := len(t)
 	if total == 0 {
 		return 0
@@ -136,12 +415,39 @@ func (j JudgeImp) CalcScore(t []structs.TestResult) int {
 	return 100 * correct / total
 }
 
+func calcGroupsScore(t []structs.TestResult, groups []structs.TestGroup) int {
+	passed := make(map[int64]bool, len(groups))
+	for _, r := range t {
+		if r.GroupID == 0 {
+			continue
+		}
+		if _, seen := passed[r.GroupID]; !seen {
+			passed[r.GroupID] = true
+		}
+		if r.Verdict != structs.VerdictOK {
+			passed[r.GroupID] = false
+		}
+	}
+
+	total, earned := 0, 0
+	for _, g := range groups {
+		total += g.Points
+		if passed[g.ID] {
+			earned += g.Points
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 * earned / total
+}
+
 func (j JudgeImp) GetScore(ctx context.Context, id string) (int, error) {
 	results, err := j.judgeRepo.GetResults(ctx, id)
 	if err != nil {
 		pkg.Log.Error("error on get score: ", err)
 		return 0, err
 	}
-	return j.CalcScore(results.TestResults), nil
+	return j.CalcScore(results.TestResults, results.Groups), nil
 
 }
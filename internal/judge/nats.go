@@ -2,48 +2,190 @@ package judge
 
 import (
 	"encoding/json"
+	"time"
+
 	"github.com/nats-io/nats.go"
+	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/configs"
 	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultStream      = "JUDGE"
+	resultStreamSuffix = "_RESULTS"
+	resultsDurable     = "result_processor"
+	progressQueue      = "progress_processor"
+	defaultAckWait     = 5 * time.Minute
+	defaultMaxDeliver  = 5
+
+	fetchRetryDelay    = time.Second
+	maxFetchRetryDelay = 30 * time.Second
 )
 
+// JudgeQueue is a durable queue on nats jetstream. judge requests are consumed by runners
+// and the results are published on another subject to be consumed by the backend.
+// messages are only removed from the streams when they are acked, so both sides can be restarted safely
 type JudgeQueue interface {
-	Send(req structs.JudgeRequest) (structs.JudgeResponse, error)
+	Send(req structs.JudgeRequest) error
 	Subscribe() (*nats.Subscription, error)
+	PublishResult(resp structs.JudgeResponse) error
+	SubscribeResults() (*nats.Subscription, error)
+	PublishProgress(progress structs.JudgeProgress) error
+	SubscribeProgress(handler func(structs.JudgeProgress)) (*nats.Subscription, error)
+	WatchProgress(handler func(structs.JudgeProgress)) (*nats.Subscription, error)
+	AckWait() time.Duration
+	Delivery(msg *nats.Msg) (uint64, bool)
 }
 
 type JudgeQueueImp struct {
 	conn   *nats.Conn
-	sub    *nats.Subscription
+	js     nats.JetStreamContext
 	config configs.SectionNats
 }
 
 func NewJudgeQueue(c configs.SectionNats) (JudgeQueue, error) {
+	if c.Stream == "" {
+		c.Stream = defaultStream
+	}
+	if c.AckWait == 0 {
+		c.AckWait = defaultAckWait
+	}
+	if c.MaxDeliver == 0 {
+		c.MaxDeliver = defaultMaxDeliver
+	}
+
 	conn, err := nats.Connect(c.Url)
 	if err != nil {
 		return nil, err
 	}
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get jetstream context")
+	}
+
 	ans := JudgeQueueImp{
 		conn:   conn,
+		js:     js,
 		config: c,
 	}
-	return ans, err
+	if err := ans.addStream(c.Stream, c.Subject); err != nil {
+		return nil, err
+	}
+	if err := ans.addStream(c.Stream+resultStreamSuffix, c.ResultSubject); err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+func (j JudgeQueueImp) addStream(name, subject string) error {
+	_, err := j.js.StreamInfo(name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return errors.Wrapf(err, "couldn't get info of stream %v", name)
+	}
+	_, err = j.js.AddStream(&nats.StreamConfig{
+		Name:      name,
+		Subjects:  []string{subject},
+		Retention: nats.WorkQueuePolicy,
+		Storage:   nats.FileStorage,
+	})
+	return errors.Wrapf(err, "couldn't add stream %v", name)
 }
 
-func (j JudgeQueueImp) Send(req structs.JudgeRequest) (resp structs.JudgeResponse, err error) {
+func (j JudgeQueueImp) Send(req structs.JudgeRequest) error {
 	data, err := json.Marshal(req)
 	if err != nil {
-		return
+		return err
 	}
-	msg, err := j.conn.Request(j.config.Subject, data, j.config.ReplyTimeout)
+	_, err = j.js.Publish(j.config.Subject, data)
+	return err
+}
+
+// Subscribe is used by runners, messages must be acked after the result is published
+func (j JudgeQueueImp) Subscribe() (*nats.Subscription, error) {
+	return j.js.PullSubscribe(j.config.Subject, j.config.Queue,
+		nats.BindStream(j.config.Stream),
+		nats.ManualAck(),
+		nats.AckWait(j.config.AckWait),
+		nats.MaxDeliver(j.config.MaxDeliver),
+	)
+}
+
+func (j JudgeQueueImp) PublishResult(resp structs.JudgeResponse) error {
+	data, err := json.Marshal(resp)
 	if err != nil {
-		return
+		return err
 	}
-	err = json.Unmarshal(msg.Data, &resp)
-	return
+	_, err = j.js.Publish(j.config.ResultSubject, data)
+	return err
+}
+
+// SubscribeResults is used by the backend, messages must be acked after the result is stored
+func (j JudgeQueueImp) SubscribeResults() (*nats.Subscription, error) {
+	return j.js.PullSubscribe(j.config.ResultSubject, resultsDurable,
+		nats.BindStream(j.config.Stream+resultStreamSuffix),
+		nats.ManualAck(),
+		nats.AckWait(j.config.AckWait),
+		nats.MaxDeliver(j.config.MaxDeliver),
+	)
+}
+
+// PublishProgress reports progress of a runner, unlike requests and results it is not durable
+func (j JudgeQueueImp) PublishProgress(progress structs.JudgeProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return j.conn.Publish(j.config.ProgressSubject, data)
 }
-func (j JudgeQueueImp) Subscribe() (*nats.Subscription, error) {
 
-	return j.conn.QueueSubscribeSync(j.config.Subject, j.config.Queue)
+// SubscribeProgress calls handler for progress reports, each report is handled by one of the subscribed backends
+func (j JudgeQueueImp) SubscribeProgress(handler func(structs.JudgeProgress)) (*nats.Subscription, error) {
+	return j.conn.QueueSubscribe(j.config.ProgressSubject, progressQueue, func(msg *nats.Msg) {
+		var progress structs.JudgeProgress
+		if err := json.Unmarshal(msg.Data, &progress); err != nil {
+			pkg.Log.Error("error on unmarshal judge progress: ", err)
+			return
+		}
+		handler(progress)
+	})
+}
+
+// WatchProgress calls handler for every progress report, unlike SubscribeProgress all of the subscribed backends get each report
+func (j JudgeQueueImp) WatchProgress(handler func(structs.JudgeProgress)) (*nats.Subscription, error) {
+	return j.conn.Subscribe(j.config.ProgressSubject, func(msg *nats.Msg) {
+		var progress structs.JudgeProgress
+		if err := json.Unmarshal(msg.Data, &progress); err != nil {
+			pkg.Log.Error("error on unmarshal judge progress: ", err)
+			return
+		}
+		handler(progress)
+	})
+}
+
+// AckWait is the time after which an unacked message is redelivered, consumers should report progress before it
+func (j JudgeQueueImp) AckWait() time.Duration {
+	return j.config.AckWait
+}
 
+// Delivery gives the number of times msg is delivered including this time and whether it won't be delivered again.
+// a message which is given up on its last delivery must be reported, otherwise its submission is never finished
+func (j JudgeQueueImp) Delivery(msg *nats.Msg) (uint64, bool) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 1, false
+	}
+	return meta.NumDelivered, j.config.MaxDeliver > 0 && meta.NumDelivered >= uint64(j.config.MaxDeliver)
+}
+
+// FetchRetryDelay is the time to wait before fetching again after the given number of failed fetches in a row,
+// so a broken queue isn't polled in a busy loop
+func FetchRetryDelay(failures int) time.Duration {
+	if failures > 5 {
+		return maxFetchRetryDelay
+	}
+	return min(fetchRetryDelay<<failures, maxFetchRetryDelay)
 }
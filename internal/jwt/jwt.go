@@ -2,15 +2,17 @@ package jwt
 
 import (
 	"encoding/json"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/configs"
-	"time"
+	"github.com/ocontest/backend/pkg/structs"
 )
 
 type TokenGenerator interface {
-	GenToken(ID int64, typ string, expireTime time.Duration) (string, error)
-	ParseToken(token string) (ID int64, typ string, err error)
+	GenToken(claims structs.TokenClaims, expireTime time.Duration) (string, error)
+	ParseToken(token string) (structs.TokenClaims, error)
 }
 
 type TokenGeneratorImp struct {
@@ -25,7 +27,7 @@ func NewGenerator(conf configs.SectionJWT) TokenGenerator {
 	}
 }
 
-func (t TokenGeneratorImp) GenToken(userID int64, typ string, expireTime time.Duration) (string, error) {
+func (t TokenGeneratorImp) GenToken(claims structs.TokenClaims, expireTime time.Duration) (string, error) {
 	if expireTime == 0 {
 		return "", pkg.ErrBadRequest
 	}
@@ -33,34 +35,54 @@ func (t TokenGeneratorImp) GenToken(userID int64, typ string, expireTime time.Du
 	mapClaims := jwt.MapClaims{}
 
 	mapClaims["iat"] = time.Now().Unix()
-	mapClaims["userID"] = userID
-	mapClaims["typ"] = typ
+	mapClaims["userID"] = claims.UserID
+	mapClaims["typ"] = claims.Type
 	mapClaims["exp"] = time.Now().Add(expireTime).Unix()
+	mapClaims["sid"] = claims.SessionID
+	mapClaims["gen"] = claims.Generation
+	if claims.TokenID != "" {
+		mapClaims["jti"] = claims.TokenID
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
 	return token.SignedString(t.secret)
 
 }
 
-func (t TokenGeneratorImp) ParseToken(token string) (int64, string, error) {
+func (t TokenGeneratorImp) ParseToken(token string) (structs.TokenClaims, error) {
 	mapClaims := jwt.MapClaims{}
 	_, err := jwt.ParseWithClaims(token, mapClaims, func(token *jwt.Token) (interface{}, error) {
 		return t.secret, nil
 	}, jwt.WithJSONNumber())
 	if err != nil {
-		return -1, "", err
+		return structs.TokenClaims{}, err
 	}
 
-	if exp, exists := mapClaims["exp"]; exists {
-		expInt, err := exp.(json.Number).Int64()
-		if err != nil || expInt < time.Now().Unix() {
-			return -1, "", pkg.ErrExpired
-		}
-		userID, err := mapClaims["userID"].(json.Number).Int64()
-		if err != nil {
-			return -1, "", pkg.ErrBadRequest
+	exp, exists := mapClaims["exp"]
+	if !exists {
+		return structs.TokenClaims{}, pkg.ErrExpired
+	}
+	expInt, err := exp.(json.Number).Int64()
+	if err != nil || expInt < time.Now().Unix() {
+		return structs.TokenClaims{}, pkg.ErrExpired
+	}
+
+	var claims structs.TokenClaims
+	userID, ok := mapClaims["userID"].(json.Number)
+	if !ok {
+		return structs.TokenClaims{}, pkg.ErrBadRequest
+	}
+	if claims.UserID, err = userID.Int64(); err != nil {
+		return structs.TokenClaims{}, pkg.ErrBadRequest
+	}
+	if gen, ok := mapClaims["gen"].(json.Number); ok {
+		if claims.Generation, err = gen.Int64(); err != nil {
+			return structs.TokenClaims{}, pkg.ErrBadRequest
 		}
-		return userID, mapClaims["typ"].(string), err
 	}
-	return -1, "", pkg.ErrExpired
+	// tokens from before sessions have no sid, they are rejected when the session is checked
+	claims.Type, _ = mapClaims["typ"].(string)
+	claims.SessionID, _ = mapClaims["sid"].(string)
+	claims.TokenID, _ = mapClaims["jti"].(string)
+	return claims, nil
 }
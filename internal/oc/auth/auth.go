@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"github.com/ocontest/backend/internal/db/repos"
 	"net/http"
+	"slices"
 
 	"github.com/ocontest/backend/internal/jwt"
+	"github.com/ocontest/backend/internal/oidc"
 	"github.com/ocontest/backend/internal/otp"
+	"github.com/ocontest/backend/internal/ratelimit"
+	"github.com/ocontest/backend/internal/session"
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/aes"
 	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/password"
 	"github.com/ocontest/backend/pkg/smtp"
 	"github.com/ocontest/backend/pkg/structs"
 	"github.com/sirupsen/logrus"
@@ -19,45 +24,61 @@ import (
 
 type AuthHandler interface {
 	RegisterUser(ctx context.Context, request structs.RegisterUserRequest) (structs.RegisterUserResponse, int)
-	VerifyEmail(ctx context.Context, userID int64, otp string) int
+	VerifyEmail(ctx context.Context, userID int64, otp string) (structs.AuthenticateResponse, int)
 	LoginWithPassword(ctx context.Context, email, password string) (structs.AuthenticateResponse, int)
-	RenewToken(ctx context.Context, userID int64, token_type string, fullRefresh bool) (structs.AuthenticateResponse, int)
+	RenewToken(ctx context.Context, claims structs.TokenClaims) (structs.AuthenticateResponse, int)
+	Logout(ctx context.Context, claims structs.TokenClaims) int
+	LogoutAll(ctx context.Context, userID int64) int
 	RequestLoginWithOTP(ctx context.Context, userID string) int
 	LoginWithOTP(ctx context.Context, email, otpCode string) (structs.AuthenticateResponse, int)
+	ForgotPassword(ctx context.Context, email string) int
+	ResetPassword(ctx context.Context, request structs.RequestResetPassword) (structs.AuthenticateResponse, int)
+	OIDCLoginURL(ctx context.Context, provider string) (string, int)
+	LoginWithOIDC(ctx context.Context, provider, code, state string) (structs.AuthenticateResponse, int)
 	EditUser(ctx context.Context, request structs.RequestEditUser) int
-	ParseAuthToken(ctx context.Context, token string) (int64, string, error)
+	ParseAuthToken(ctx context.Context, token string) (structs.TokenClaims, error)
 	GetUser(ctx context.Context, userID int64, getPrivate bool) (structs.ReponeGetUser, int)
+	SetRole(ctx context.Context, userID int64, role string) int
 }
 
 type AuthHandlerImp struct {
-	authRepo   repos.UsersRepo
-	jwtHandler jwt.TokenGenerator
-	smtpSender smtp.Sender
-	configs    *configs.OContestConf
-	aesHandler aes.AESHandler
-	otpStorage otp.OTPHandler
+	authRepo        repos.UsersRepo
+	jwtHandler      jwt.TokenGenerator
+	smtpSender      smtp.Sender
+	configs         *configs.OContestConf
+	aesHandler      aes.AESHandler // only used to check the passwords stored before hashing
+	passwordHandler password.PasswordHandler
+	otpStorage      otp.OTPHandler
+	sessionHandler  session.SessionHandler
+	loginLimiter    ratelimit.Limiter // counts failed logins of users to lock them out
+	oidcHandler     oidc.Handler
 }
 
 func NewAuthHandler(
 	authRepo repos.UsersRepo, jwtHandler jwt.TokenGenerator,
 	smtpSender smtp.Sender, config *configs.OContestConf,
-	aesHandler aes.AESHandler, otpStorage otp.OTPHandler) AuthHandler {
+	aesHandler aes.AESHandler, passwordHandler password.PasswordHandler, otpStorage otp.OTPHandler,
+	sessionHandler session.SessionHandler, loginLimiter ratelimit.Limiter, oidcHandler oidc.Handler) AuthHandler {
 	return &AuthHandlerImp{
-		authRepo:   authRepo,
-		jwtHandler: jwtHandler,
-		smtpSender: smtpSender,
-		configs:    config,
-		aesHandler: aesHandler,
-		otpStorage: otpStorage,
+		authRepo:        authRepo,
+		jwtHandler:      jwtHandler,
+		smtpSender:      smtpSender,
+		configs:         config,
+		aesHandler:      aesHandler,
+		passwordHandler: passwordHandler,
+		otpStorage:      otpStorage,
+		sessionHandler:  sessionHandler,
+		loginLimiter:    loginLimiter,
+		oidcHandler:     oidcHandler,
 	}
 }
 
 func (p *AuthHandlerImp) RegisterUser(ctx context.Context, reqData structs.RegisterUserRequest) (ans structs.RegisterUserResponse, status int) {
 	logger := pkg.Log.WithField("method", "RegisterUser")
 
-	encryptedPassword, err := p.aesHandler.Encrypt(reqData.Password)
+	encryptedPassword, err := p.passwordHandler.Hash(reqData.Password)
 	if err != nil {
-		logger.Error("error on encrypting password", err)
+		logger.Error("error on hashing password", err)
 		status = 503
 		ans.Message = "something went wrong, please try again later."
 		return
@@ -92,8 +113,7 @@ func (p *AuthHandlerImp) RegisterUser(ctx context.Context, reqData structs.Regis
 		return
 	}
 
-	validateEmailMessage := p.genEmailMessage(user, otpCode, Register)
-	err = p.smtpSender.SendEmail(reqData.Email, "Welcome to OContest", validateEmailMessage)
+	err = p.smtpSender.SendEmail(reqData.Email, "Welcome to OContest", "register", emailData{Username: user.Username, Code: otpCode})
 	if err != nil {
 		logger.Error("error on sending email", err)
 		status = 503
@@ -109,24 +129,31 @@ func (p *AuthHandlerImp) RegisterUser(ctx context.Context, reqData structs.Regis
 	return
 }
 
-func (p *AuthHandlerImp) VerifyEmail(ctx context.Context, userID int64, token string) int {
+// VerifyEmail checks the otp sent by RegisterUser. wrong codes count as failed logins, since registering again
+// makes a new code and resets its attempts. the response is only set for a locked account
+func (p *AuthHandlerImp) VerifyEmail(ctx context.Context, userID int64, token string) (structs.AuthenticateResponse, int) {
 
 	logger := pkg.Log.WithField("method", "VerifyEmail")
+	if resp, locked := p.checkLockout(ctx, userID); locked {
+		return resp, http.StatusTooManyRequests
+	}
 	userIDStr := fmt.Sprintf("%d", userID)
 	if err := p.otpStorage.Check(ctx, userIDStr, "register", token); err != nil {
-		if errors.Is(err, pkg.ErrForbidden) {
-			return http.StatusForbidden
+		if errors.Is(err, pkg.ErrForbidden) || errors.Is(err, pkg.ErrNotFound) {
+			p.countFailedLogin(ctx, userID)
+			return structs.AuthenticateResponse{}, http.StatusForbidden
 		}
 		logger.Error("error on check register otp", err)
-		return http.StatusInternalServerError
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
 	}
 
 	if err := p.authRepo.VerifyUser(ctx, userID); err != nil {
 		logger.Error("error on verifying user", err)
 
-		return http.StatusInternalServerError
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
 	}
-	return http.StatusOK
+	p.resetFailedLogins(ctx, userID)
+	return structs.AuthenticateResponse{}, http.StatusOK
 }
 
 func (p *AuthHandlerImp) LoginWithPassword(ctx context.Context, email, password string) (structs.AuthenticateResponse, int) {
@@ -143,6 +170,9 @@ func (p *AuthHandlerImp) LoginWithPassword(ctx context.Context, email, password
 			Message: "couldn't find user",
 		}, http.StatusInternalServerError
 	}
+	if resp, locked := p.checkLockout(ctx, userInDB.ID); locked {
+		return resp, http.StatusTooManyRequests
+	}
 	if !userInDB.Verified {
 		logger.Warning("unverified user login attempt", userInDB.Username)
 		return structs.AuthenticateResponse{
@@ -150,22 +180,23 @@ func (p *AuthHandlerImp) LoginWithPassword(ctx context.Context, email, password
 			Message: "user is not verified",
 		}, http.StatusForbidden
 	}
-	encPassword, err := p.aesHandler.Encrypt(password)
-	if err != nil {
-		logger.Error("error on encrypting password")
+	if err := p.checkPassword(ctx, userInDB, password); err != nil {
+		if errors.Is(err, pkg.ErrForbidden) {
+			logger.Warning("wrong password")
+			p.countFailedLogin(ctx, userInDB.ID)
+			return structs.AuthenticateResponse{
+				Ok:      false,
+				Message: "wrong password",
+			}, http.StatusUnauthorized
+		}
+		logger.Error("error on checking password: ", err)
 		return structs.AuthenticateResponse{
 			Ok:      false,
 			Message: "something went wrong",
 		}, http.StatusInternalServerError
 	}
-	if encPassword != userInDB.EncryptedPassword {
-		logger.Warning("wrong password")
-		return structs.AuthenticateResponse{
-			Ok:      false,
-			Message: "wrong password",
-		}, http.StatusUnauthorized
-	}
-	accessToken, refreshToken, err := p.genAuthToken(userInDB.ID)
+	p.resetFailedLogins(ctx, userInDB.ID)
+	accessToken, refreshToken, err := p.genAuthToken(ctx, userInDB.ID)
 	if err != nil {
 		logger.Error("error on creating tokens", err)
 		return structs.AuthenticateResponse{
@@ -181,23 +212,44 @@ func (p *AuthHandlerImp) LoginWithPassword(ctx context.Context, email, password
 	}, http.StatusOK
 }
 
-func (p *AuthHandlerImp) RenewToken(ctx context.Context, userID int64, tokenType string, fullRefresh bool) (structs.AuthenticateResponse, int) {
-	if tokenType != "refresh" {
+// RenewToken gives new tokens for a refresh token, the refresh token is replaced by the new one and
+// using it again revokes its session
+func (p *AuthHandlerImp) RenewToken(ctx context.Context, claims structs.TokenClaims) (structs.AuthenticateResponse, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "RenewToken",
+		"module": "auth",
+	})
+
+	if claims.Type != "refresh" {
 		return structs.AuthenticateResponse{
 			Ok:      false,
 			Message: "invalid token type",
 		}, http.StatusBadRequest
 	}
 
-	accessToken, refreshToken, err := p.genAuthToken(userID)
+	newClaims, err := p.sessionHandler.Rotate(ctx, claims)
 	if err != nil {
+		if errors.Is(err, pkg.ErrForbidden) {
+			logger.Warningf("reused refresh token of user %v, session %v is revoked", claims.UserID, claims.SessionID)
+			return structs.AuthenticateResponse{
+				Ok:      false,
+				Message: "refresh token is revoked",
+			}, http.StatusUnauthorized
+		}
+		logger.Error("error on rotating refresh token: ", err)
 		return structs.AuthenticateResponse{
 			Ok:      false,
 			Message: "couldn't generate new token",
 		}, http.StatusInternalServerError
 	}
-	if !fullRefresh {
-		refreshToken = ""
+
+	accessToken, refreshToken, err := p.genSessionTokens(newClaims)
+	if err != nil {
+		logger.Error("error on creating tokens: ", err)
+		return structs.AuthenticateResponse{
+			Ok:      false,
+			Message: "couldn't generate new token",
+		}, http.StatusInternalServerError
 	}
 	return structs.AuthenticateResponse{
 		Ok:           true,
@@ -223,8 +275,7 @@ func (p *AuthHandlerImp) RequestLoginWithOTP(ctx context.Context, email string)
 		logger.Error("error on generating otp", err)
 		return
 	}
-	validateEmailMessage := p.genEmailMessage(user, otpCode, Login)
-	err = p.smtpSender.SendEmail(user.Email, "Your one time password", validateEmailMessage)
+	err = p.smtpSender.SendEmail(user.Email, "Your one time password", "login", emailData{Username: user.Username, Code: otpCode})
 	if err != nil {
 		logger.Error("error on sending email", err)
 		status = 503
@@ -245,9 +296,13 @@ func (p *AuthHandlerImp) LoginWithOTP(ctx context.Context, email, otpCode string
 		logger.Error("error on getting user from db: ", err)
 		return
 	}
+	if resp, locked := p.checkLockout(ctx, user.ID); locked {
+		return resp, http.StatusTooManyRequests
+	}
 	userIDStr := fmt.Sprintf("%d", user.ID)
 	if err := p.otpStorage.Check(ctx, userIDStr, "login", otpCode); err != nil {
 		if errors.Is(err, pkg.ErrForbidden) || errors.Is(err, pkg.ErrNotFound) {
+			p.countFailedLogin(ctx, user.ID)
 			status = http.StatusForbidden
 			return
 		}
@@ -260,7 +315,8 @@ func (p *AuthHandlerImp) LoginWithOTP(ctx context.Context, email, otpCode string
 		return
 	}
 
-	accessToken, refreshToken, err := p.genAuthToken(user.ID)
+	p.resetFailedLogins(ctx, user.ID)
+	accessToken, refreshToken, err := p.genAuthToken(ctx, user.ID)
 	if err != nil {
 		ans = structs.AuthenticateResponse{
 			Ok:      false,
@@ -278,31 +334,224 @@ func (p *AuthHandlerImp) LoginWithOTP(ctx context.Context, email, otpCode string
 
 }
 
-func (a *AuthHandlerImp) EditUser(ctx context.Context, request structs.RequestEditUser) int {
-	logger := pkg.Log.WithField("method", "EditUser")
+// ForgotPassword sends an otp for resetting the password to the email. it doesn't tell whether there is
+// a user with the email
+func (p *AuthHandlerImp) ForgotPassword(ctx context.Context, email string) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "ForgotPassword",
+		"module": "auth",
+	})
 
-	encryptedPassword, err := a.aesHandler.Encrypt(request.Password)
+	user, err := p.authRepo.GetByEmail(ctx, email)
 	if err != nil {
-		logger.Error("error on encrypting password: ", err)
+		logger.Warningf("forgot password of unknown email %v: %v", email, err)
+		return http.StatusOK
+	}
+
+	userIDStr := fmt.Sprintf("%d", user.ID)
+	otpCode, err := p.otpStorage.Gen(ctx, userIDStr, "reset")
+	if err != nil {
+		logger.Error("error on generating otp: ", err)
 		return http.StatusInternalServerError
 	}
+	err = p.smtpSender.SendEmail(user.Email, "Reset your password", "reset", emailData{Username: user.Username, Code: otpCode})
+	if err != nil {
+		logger.Error("error on sending email: ", err)
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// ResetPassword sets the new password of the user if the otp of ForgotPassword is right, all of the
+// sessions of the user are revoked. wrong codes count as failed logins, since ForgotPassword makes a new code
+// and resets its attempts. the response is only set for a locked account
+func (p *AuthHandlerImp) ResetPassword(ctx context.Context, request structs.RequestResetPassword) (structs.AuthenticateResponse, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "ResetPassword",
+		"module": "auth",
+	})
+
+	if request.Password == "" {
+		return structs.AuthenticateResponse{}, http.StatusBadRequest
+	}
+	user, err := p.authRepo.GetByEmail(ctx, request.Email)
+	if err != nil {
+		logger.Warningf("reset password of unknown email %v: %v", request.Email, err)
+		return structs.AuthenticateResponse{}, http.StatusForbidden
+	}
+	if resp, locked := p.checkLockout(ctx, user.ID); locked {
+		return resp, http.StatusTooManyRequests
+	}
+
+	userIDStr := fmt.Sprintf("%d", user.ID)
+	if err := p.otpStorage.Check(ctx, userIDStr, "reset", request.OTP); err != nil {
+		if errors.Is(err, pkg.ErrForbidden) || errors.Is(err, pkg.ErrNotFound) {
+			logger.Warningf("wrong reset otp of user %v", user.ID)
+			p.countFailedLogin(ctx, user.ID)
+			return structs.AuthenticateResponse{}, http.StatusForbidden
+		}
+		logger.Error("error on check reset otp: ", err)
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
+	}
+
+	hash, err := p.passwordHandler.Hash(request.Password)
+	if err != nil {
+		logger.Error("error on hashing password: ", err)
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
+	}
+	if err := p.authRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		logger.Error("error on updating password: ", err)
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
+	}
+	// the code is sent to the email, so the email is verified too
+	if err := p.authRepo.VerifyUser(ctx, user.ID); err != nil {
+		logger.Error("error on verifying user: ", err)
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
+	}
+	if err := p.sessionHandler.RevokeAll(ctx, user.ID); err != nil {
+		logger.Error("error on revoking sessions: ", err)
+		return structs.AuthenticateResponse{}, http.StatusInternalServerError
+	}
+	p.resetFailedLogins(ctx, user.ID)
+
+	logger.Infof("password of user %v is reset", user.ID)
+	return structs.AuthenticateResponse{}, http.StatusOK
+}
+
+// OIDCLoginURL gives the login page of the provider, which redirects the user back to LoginWithOIDC
+func (p *AuthHandlerImp) OIDCLoginURL(ctx context.Context, provider string) (string, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "OIDCLoginURL",
+		"module": "auth",
+	})
+
+	loginURL, err := p.oidcHandler.Begin(ctx, provider)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return "", http.StatusNotFound
+	} else if err != nil {
+		logger.Errorf("error on beginning login with %v: %v", provider, err)
+		return "", http.StatusBadGateway
+	}
+	return loginURL, http.StatusFound
+}
+
+// LoginWithOIDC logs in the user that is logged in by the provider. the user is linked to the user with the
+// same email, or is made on its first login. only verified emails are accepted, since the email is trusted
+func (p *AuthHandlerImp) LoginWithOIDC(ctx context.Context, provider, code, state string) (structs.AuthenticateResponse, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "LoginWithOIDC",
+		"module": "auth",
+	})
+
+	identity, err := p.oidcHandler.Finish(ctx, provider, code, state)
+	if err != nil {
+		switch {
+		case errors.Is(err, pkg.ErrNotFound):
+			return structs.AuthenticateResponse{Message: "unknown provider"}, http.StatusNotFound
+		case errors.Is(err, pkg.ErrForbidden):
+			logger.Warningf("failed login with %v: %v", provider, err)
+			return structs.AuthenticateResponse{Message: "login is not accepted"}, http.StatusForbidden
+		default:
+			logger.Errorf("error on login with %v: %v", provider, err)
+			return structs.AuthenticateResponse{Message: "couldn't reach the provider"}, http.StatusBadGateway
+		}
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		logger.Warningf("login with %v of subject %v without a verified email", provider, identity.Subject)
+		return structs.AuthenticateResponse{Message: "email is not verified by the provider"}, http.StatusForbidden
+	}
+
+	user, err := p.oidcUser(ctx, identity)
+	if err != nil {
+		logger.Error("error on getting user of oidc login: ", err)
+		return structs.AuthenticateResponse{Message: "something went wrong, please try again later."}, http.StatusInternalServerError
+	}
+	if resp, locked := p.checkLockout(ctx, user.ID); locked {
+		return resp, http.StatusTooManyRequests
+	}
+
+	accessToken, refreshToken, err := p.genAuthToken(ctx, user.ID)
+	if err != nil {
+		logger.Error("error on generating token: ", err)
+		return structs.AuthenticateResponse{Message: "couldn't generate new token"}, http.StatusInternalServerError
+	}
+	return structs.AuthenticateResponse{
+		Ok:           true,
+		Message:      "success",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, http.StatusOK
+}
+
+func (a *AuthHandlerImp) EditUser(ctx context.Context, request structs.RequestEditUser) int {
+	logger := pkg.Log.WithField("method", "EditUser")
 
 	user := structs.User{
-		ID:                request.UserID,
-		Username:          request.Username,
-		Email:             request.Email,
-		EncryptedPassword: encryptedPassword,
+		ID:       request.UserID,
+		Username: request.Username,
+		Email:    request.Email,
+	}
+	if request.Password != "" {
+		hash, err := a.passwordHandler.Hash(request.Password)
+		if err != nil {
+			logger.Error("error on hashing password: ", err)
+			return http.StatusInternalServerError
+		}
+		user.EncryptedPassword = hash
 	}
 	if err := a.authRepo.UpdateUser(ctx, user); err != nil {
 		logger.Error("error on update user in pg: ", err)
 		return http.StatusInternalServerError
 	}
+	// sessions logged in with the old password must not outlive it
+	if request.Password != "" {
+		if err := a.sessionHandler.RevokeAll(ctx, request.UserID); err != nil {
+			logger.Error("error on revoking sessions: ", err)
+			return http.StatusInternalServerError
+		}
+	}
+
+	return http.StatusOK
+}
+
+// Logout revokes the session of the token
+func (a *AuthHandlerImp) Logout(ctx context.Context, claims structs.TokenClaims) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "Logout",
+		"module": "auth",
+	})
+
+	if err := a.sessionHandler.Revoke(ctx, claims.SessionID); err != nil {
+		logger.Error("error on revoking session: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// LogoutAll revokes all of the sessions of the user
+func (a *AuthHandlerImp) LogoutAll(ctx context.Context, userID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "LogoutAll",
+		"module": "auth",
+	})
 
+	if err := a.sessionHandler.RevokeAll(ctx, userID); err != nil {
+		logger.Error("error on revoking sessions: ", err)
+		return http.StatusInternalServerError
+	}
 	return http.StatusOK
 }
 
-func (a *AuthHandlerImp) ParseAuthToken(_ context.Context, token string) (int64, string, error) {
-	return a.jwtHandler.ParseToken(token)
+// ParseAuthToken parses the token and checks that its session is not revoked
+func (a *AuthHandlerImp) ParseAuthToken(ctx context.Context, token string) (structs.TokenClaims, error) {
+	claims, err := a.jwtHandler.ParseToken(token)
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	if err := a.sessionHandler.Check(ctx, claims); err != nil {
+		return structs.TokenClaims{}, err
+	}
+	return claims, nil
 }
 
 func (a *AuthHandlerImp) GetUser(ctx context.Context, userID int64, getPrivate bool) (ans structs.ReponeGetUser, status int) {
@@ -319,6 +568,7 @@ func (a *AuthHandlerImp) GetUser(ctx context.Context, userID int64, getPrivate b
 	}
 
 	ans.Username = user.Username
+	ans.Role = user.Role
 	if getPrivate {
 		ans.Email = user.Email
 	}
@@ -326,3 +576,27 @@ func (a *AuthHandlerImp) GetUser(ctx context.Context, userID int64, getPrivate b
 	status = http.StatusOK
 	return
 }
+
+// SetRole changes the role of the user, it is one of structs.Roles
+func (a *AuthHandlerImp) SetRole(ctx context.Context, userID int64, role string) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "SetRole",
+		"module": "auth",
+	})
+
+	if !slices.Contains(structs.Roles, role) {
+		logger.Warning("set unknown role: ", role)
+		return http.StatusBadRequest
+	}
+	if _, err := a.authRepo.GetByID(ctx, userID); err != nil {
+		logger.Warningf("set role of unknown user %v: %v", userID, err)
+		return http.StatusNotFound
+	}
+
+	if err := a.authRepo.SetRole(ctx, userID, role); err != nil {
+		logger.Error("error on setting role of user: ", err)
+		return http.StatusInternalServerError
+	}
+	logger.Infof("role of user %v is changed to %v", userID, role)
+	return http.StatusOK
+}
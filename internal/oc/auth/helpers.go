@@ -1,56 +1,183 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/ocontest/backend/internal/oidc"
+	"github.com/ocontest/backend/internal/ratelimit"
+	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/structs"
 )
 
-const registerMessageTemplate = `
-Hello %v! Welcome to the ocontest
-please enter the code below to verify your email address
-Code: %v
-ignore this email if you haven't tried to register to ocontest
-`
+// emailData is given to the email templates of smtp.Sender
+type emailData struct {
+	Username string
+	Code     string
+}
 
-const loginMessageTemplate = `
-Hello %v! Welcome to the ocontest
-please enter the code below to login to your account
-Code: %v
-`
+// genAuthToken will just try to generate tokens of a new session, it doesn't do any authentication and they should be done before calling this method
+func (a *AuthHandlerImp) genAuthToken(ctx context.Context, userID int64) (string, string, error) {
+	claims, err := a.sessionHandler.Start(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	return a.genSessionTokens(claims)
+}
 
-type Operation int
+// genSessionTokens generates the access token and the refresh token of the session in claims
+func (a *AuthHandlerImp) genSessionTokens(claims structs.TokenClaims) (string, string, error) {
+	accessClaims := claims
+	accessClaims.Type = "access"
+	accessClaims.TokenID = ""
+	accessToken, err := a.jwtHandler.GenToken(accessClaims, a.configs.Auth.Duration.AccessToken)
+	if err != nil {
+		return "", "", err
+	}
+	claims.Type = "refresh"
+	refreshToken, err := a.jwtHandler.GenToken(claims, a.configs.Auth.Duration.RefreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
 
-const (
-	Register Operation = iota
-	Login
-)
+// checkPassword gives pkg.ErrForbidden if the password is not the password of the user. passwords stored by aes
+// before hashing are replaced by their hash on the first successful check
+func (a *AuthHandlerImp) checkPassword(ctx context.Context, user structs.User, password string) error {
+	if user.EncryptedPassword == "" {
+		return pkg.ErrForbidden
+	}
+	if a.passwordHandler.IsHashed(user.EncryptedPassword) {
+		return a.passwordHandler.Compare(user.EncryptedPassword, password)
+	}
 
-func (a *AuthHandlerImp) genEmailMessage(user structs.User, otpCode string, operation Operation) string {
-	var template string
-	switch operation {
-	case Register:
-		template = registerMessageTemplate
-	case Login:
-		template = loginMessageTemplate
-	default:
-		return ""
+	encPassword, err := a.aesHandler.Encrypt(password)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(encPassword), []byte(user.EncryptedPassword)) != 1 {
+		return pkg.ErrForbidden
 	}
-	return fmt.Sprintf(template,
-		user.Username,
-		otpCode,
-	)
 
+	// the password is right, failing to migrate it shouldn't fail the login
+	logger := pkg.Log.WithField("method", "checkPassword")
+	hash, err := a.passwordHandler.Hash(password)
+	if err != nil {
+		logger.Error("error on hashing password: ", err)
+		return nil
+	}
+	if err := a.authRepo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		logger.Errorf("error on migrating password of user %v: %v", user.ID, err)
+		return nil
+	}
+	logger.Infof("password of user %v is migrated to hash", user.ID)
+	return nil
 }
 
-// genAuthToken will just try to generate tokens, it doesn't do any authentication and they should be done before calling this method
-func (a *AuthHandlerImp) genAuthToken(userID int64) (string, string, error) {
-	accessToken, err := a.jwtHandler.GenToken(userID, "access", a.configs.Auth.Duration.AccessToken)
+// checkLockout tells whether the user is locked out for too many failed logins. errors of the limiter don't lock
+// anyone out, they are only logged
+func (a *AuthHandlerImp) checkLockout(ctx context.Context, userID int64) (structs.AuthenticateResponse, bool) {
+	logger := pkg.Log.WithField("method", "checkLockout")
+
+	wait, err := a.loginLimiter.Blocked(ctx, fmt.Sprintf("%d", userID))
 	if err != nil {
-		return "", "", err
+		logger.Error("error on checking failed logins: ", err)
+		return structs.AuthenticateResponse{}, false
 	}
-	refreshToken, err := a.jwtHandler.GenToken(userID, "refresh", a.configs.Auth.Duration.RefreshToken)
+	if wait == 0 {
+		return structs.AuthenticateResponse{}, false
+	}
+	logger.Warningf("login attempt of locked user %v", userID)
+	return structs.AuthenticateResponse{
+		Ok:         false,
+		Message:    "too many failed logins, try again later",
+		RetryAfter: ratelimit.Seconds(wait),
+	}, true
+}
+
+func (a *AuthHandlerImp) countFailedLogin(ctx context.Context, userID int64) {
+	if _, err := a.loginLimiter.Hit(ctx, fmt.Sprintf("%d", userID)); err != nil {
+		pkg.Log.WithField("method", "countFailedLogin").Error("error on counting failed login: ", err)
+	}
+}
+
+func (a *AuthHandlerImp) resetFailedLogins(ctx context.Context, userID int64) {
+	if err := a.loginLimiter.Reset(ctx, fmt.Sprintf("%d", userID)); err != nil {
+		pkg.Log.WithField("method", "resetFailedLogins").Error("error on resetting failed logins: ", err)
+	}
+}
+
+// invalidUsernameChars are removed from the usernames suggested by oidc providers
+var invalidUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// oidcUser gives the user with the verified email of the identity, or makes it on the first login.
+// an unverified user with the email may be registered by anyone, so its password is removed before linking
+func (a *AuthHandlerImp) oidcUser(ctx context.Context, identity oidc.Identity) (structs.User, error) {
+	logger := pkg.Log.WithField("method", "oidcUser")
+
+	user, getErr := a.authRepo.GetByEmail(ctx, identity.Email)
+	if getErr == nil {
+		if !user.Verified {
+			if err := a.authRepo.UpdatePassword(ctx, user.ID, ""); err != nil {
+				return structs.User{}, err
+			}
+			if err := a.authRepo.VerifyUser(ctx, user.ID); err != nil {
+				return structs.User{}, err
+			}
+			logger.Infof("unverified user %v is linked to oidc login and its password is removed", user.ID)
+		}
+		return user, nil
+	}
+
+	username, err := a.freeUsername(ctx, identity)
 	if err != nil {
-		return "", "", err
+		return structs.User{}, err
 	}
-	return accessToken, refreshToken, nil
+	user = structs.User{
+		Username: username,
+		Email:    identity.Email,
+		Verified: true,
+	}
+	userID, newErr := a.authRepo.InsertUser(ctx, user)
+	if newErr != nil {
+		return structs.User{}, fmt.Errorf("couldn't insert user, error on get: %v, error on insert: %w", getErr, newErr)
+	}
+	// users are inserted unverified
+	if err := a.authRepo.VerifyUser(ctx, userID); err != nil {
+		return structs.User{}, err
+	}
+	user.ID = userID
+	logger.Infof("user %v is made by oidc login", userID)
+	return user, nil
+}
+
+// freeUsername gives the username suggested by the provider, or the name of the email, with a random
+// number if it is taken
+func (a *AuthHandlerImp) freeUsername(ctx context.Context, identity oidc.Identity) (string, error) {
+	base := invalidUsernameChars.ReplaceAllString(identity.Username, "")
+	if base == "" {
+		base = invalidUsernameChars.ReplaceAllString(strings.Split(identity.Email, "@")[0], "")
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		if _, err := a.authRepo.GetByUsername(ctx, username); err != nil {
+			return username, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(100000))
+		if err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s%05d", base, n.Int64())
+	}
+	return "", fmt.Errorf("couldn't find a free username for %v", base)
 }
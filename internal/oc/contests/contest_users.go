@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/structs"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"slices"
+	"time"
 )
 
 func (c ContestsHandlerImp) RegisterUser(ctx context.Context, contestID, userID int64) int {
@@ -14,7 +17,18 @@ func (c ContestsHandlerImp) RegisterUser(ctx context.Context, contestID, userID
 		"method": "RegisterUser",
 	})
 
-	err := c.contestsUsersRepo.Add(ctx, contestID, userID)
+	// after the end only virtual participation is possible, so the official ranking can't change anymore
+	ended, err := c.contestsRepo.HasEnded(ctx, contestID)
+	if err != nil {
+		logger.Error("error on check to contests metadata repo: ", err)
+		return http.StatusInternalServerError
+	}
+	if ended {
+		logger.Warningf("register in a finished contest, user id: %v, contest id: %v", userID, contestID)
+		return http.StatusBadRequest
+	}
+
+	err = c.contestsUsersRepo.Add(ctx, contestID, userID)
 	if err != nil {
 		logger.Error("error on insert to db: ", err)
 		return http.StatusInternalServerError
@@ -28,7 +42,18 @@ func (c ContestsHandlerImp) UnregisterUser(ctx context.Context, contestID, userI
 		"method": "UnregisterUser",
 	})
 
-	err := c.contestsUsersRepo.Delete(ctx, contestID, userID)
+	// a virtual participant could otherwise register again and bring the virtual results to the official ranking
+	ended, err := c.contestsRepo.HasEnded(ctx, contestID)
+	if err != nil {
+		logger.Error("error on check to contests metadata repo: ", err)
+		return http.StatusInternalServerError
+	}
+	if ended {
+		logger.Warningf("unregister from a finished contest, user id: %v, contest id: %v", userID, contestID)
+		return http.StatusBadRequest
+	}
+
+	err = c.contestsUsersRepo.Delete(ctx, contestID, userID)
 	if err != nil {
 		logger.Error("error on insert to db: ", err)
 		if errors.Is(err, pkg.ErrNotFound) {
@@ -39,3 +64,106 @@ func (c ContestsHandlerImp) UnregisterUser(ctx context.Context, contestID, userI
 
 	return http.StatusOK
 }
+
+// RegisterVirtual lets the user take a finished contest from now on, the submissions of the user count in the
+// virtual standings until the duration of the contest is passed
+func (c ContestsHandlerImp) RegisterVirtual(ctx context.Context, contestID, userID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"module": "contest",
+		"method": "RegisterVirtual",
+	})
+
+	contest, err := c.contestsRepo.GetContest(ctx, contestID)
+	if err != nil {
+		logger.Error("error on getting contest from repos: ", err)
+		if errors.Is(err, pkg.ErrNotFound) {
+			return http.StatusNotFound
+		}
+		return http.StatusInternalServerError
+	}
+
+	now := time.Now().Unix()
+	if now < contest.StartTime+int64(contest.Duration) {
+		logger.Warningf("virtual participation in a contest which is not finished, user id: %v, contest id: %v", userID, contestID)
+		return http.StatusBadRequest
+	}
+
+	registered, err := c.contestsUsersRepo.IsRegistered(ctx, contestID, userID)
+	if err != nil {
+		logger.Error("error on check to contests users repo: ", err)
+		return http.StatusInternalServerError
+	}
+	if registered {
+		logger.Warningf("user %v is already a participant of contest %v", userID, contestID)
+		return http.StatusConflict
+	}
+
+	err = c.contestsUsersRepo.AddVirtual(ctx, contestID, userID, now-contest.StartTime)
+	if err != nil {
+		logger.Error("error on insert to db: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+func (c ContestsHandlerImp) ListStaff(ctx context.Context, contestID int64) ([]structs.ContestStaff, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"module": "contest",
+		"method": "ListStaff",
+	})
+
+	staff, err := c.contestsUsersRepo.ListStaff(ctx, contestID)
+	if err != nil {
+		logger.Error("error on getting staff from db: ", err)
+		return nil, http.StatusInternalServerError
+	}
+	return staff, http.StatusOK
+}
+
+// AddStaff gives a role in the contest to the user, the role of a user who is already in the staff is changed
+func (c ContestsHandlerImp) AddStaff(ctx context.Context, contestID int64, req structs.RequestAddContestStaff) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"module": "contest",
+		"method": "AddStaff",
+	})
+
+	if !slices.Contains(structs.ContestRoles, req.Role) {
+		logger.Warning("add staff with unknown role: ", req.Role)
+		return http.StatusBadRequest
+	}
+	if _, err := c.usersRepo.GetByID(ctx, req.UserID); err != nil {
+		logger.Warningf("add staff with unknown user %v: %v", req.UserID, err)
+		return http.StatusNotFound
+	}
+
+	err := c.contestsUsersRepo.AddStaff(ctx, contestID, req.UserID, req.Role)
+	if err != nil {
+		logger.Error("error on insert to db: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+func (c ContestsHandlerImp) RemoveStaff(ctx context.Context, contestID, userID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"module": "contest",
+		"method": "RemoveStaff",
+	})
+
+	err := c.contestsUsersRepo.DeleteStaff(ctx, contestID, userID)
+	if err != nil {
+		logger.Error("error on delete from db: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// isStaff tells whether the user can test the contest, the staff see the problems before the start
+// and the live scoreboard
+func (c ContestsHandlerImp) isStaff(ctx context.Context, userID, contestID int64) (bool, error) {
+	err := c.authorizer.CanTestContest(ctx, userID, contestID)
+	if errors.Is(err, pkg.ErrForbidden) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"github.com/ocontest/backend/internal/db/repos"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/ocontest/backend/internal/authz"
 	"github.com/ocontest/backend/internal/judge"
 
 	"github.com/gin-gonic/gin"
@@ -27,7 +29,12 @@ type ContestsHandler interface {
 	RemoveProblemFromContest(ctx context.Context, contestID, problemID int64) (status int)
 	RegisterUser(ctx context.Context, contestID, userID int64) int
 	UnregisterUser(ctx context.Context, contestID, userID int64) int
-	IsContestOwner(ctx context.Context, contestID, userID int64) (bool, error)
+	RegisterVirtual(ctx context.Context, contestID, userID int64) int
+	RevealSubmission(ctx context.Context, contestID int64) (structs.ResponseRevealSubmission, int)
+	Unfreeze(ctx context.Context, contestID int64) int
+	ListStaff(ctx context.Context, contestID int64) ([]structs.ContestStaff, int)
+	AddStaff(ctx context.Context, contestID int64, req structs.RequestAddContestStaff) int
+	RemoveStaff(ctx context.Context, contestID, userID int64) int
 }
 
 type ContestsHandlerImp struct {
@@ -38,14 +45,15 @@ type ContestsHandlerImp struct {
 	contestProblemRepo repos.ContestsProblemsRepo
 	contestsUsersRepo  repos.ContestsUsersRepo
 
-	judge judge.Judge
+	judge      judge.Judge
+	authorizer authz.Authorizer
 }
 
 func NewContestsHandler(
 	contestsRepo repos.ContestsMetadataRepo, contestProblemRepo repos.ContestsProblemsRepo,
 	problemsRepo repos.ProblemsMetadataRepo, submissionsRepo repos.SubmissionMetadataRepo,
 	authRepo repos.UsersRepo, contestUsersRepo repos.ContestsUsersRepo,
-	judge judge.Judge,
+	judge judge.Judge, authorizer authz.Authorizer,
 ) ContestsHandler {
 	return &ContestsHandlerImp{
 		problemsRepo:       problemsRepo,
@@ -55,16 +63,32 @@ func NewContestsHandler(
 		contestsUsersRepo:  contestUsersRepo,
 		usersRepo:          authRepo,
 		judge:              judge,
+		authorizer:         authorizer,
 	}
 }
 
 func (c ContestsHandlerImp) CreateContest(ctx context.Context, req structs.RequestCreateContest) (res structs.ResponseCreateContest, status int) {
 	logger := pkg.Log.WithField("method", "create_contest")
+	if req.Type == "" {
+		req.Type = structs.ContestTypeScore
+	}
+	if !slices.Contains(structs.ContestTypes, req.Type) {
+		logger.Warning("create contest with unknown type: ", req.Type)
+		status = http.StatusBadRequest
+		return
+	}
+	if req.FreezeTime != 0 && req.FreezeTime < req.StartTime {
+		logger.Warning("create contest with freeze time before start: ", req.FreezeTime)
+		status = http.StatusBadRequest
+		return
+	}
 	contest := structs.Contest{
-		CreatedBy: ctx.Value("user_id").(int64),
-		Title:     req.Title,
-		StartTime: req.StartTime,
-		Duration:  req.Duration,
+		CreatedBy:  ctx.Value("user_id").(int64),
+		Title:      req.Title,
+		StartTime:  req.StartTime,
+		Duration:   req.Duration,
+		Type:       req.Type,
+		FreezeTime: req.FreezeTime,
 	}
 	var err error
 	res.ContestID, err = c.contestsRepo.InsertContest(ctx, contest)
@@ -132,7 +156,14 @@ func (c ContestsHandlerImp) GetContest(ctx *gin.Context, contestID, userID int64
 	}
 
 	if status != structs.Owner && contest.StartTime > time.Now().Unix() {
-		problems = nil
+		isStaff, err := c.isStaff(ctx, userID, contestID)
+		if err != nil {
+			logger.Error("error on checking contest staff: ", err)
+			return structs.ResponseGetContest{}, http.StatusInternalServerError
+		}
+		if !isStaff {
+			problems = nil
+		}
 	}
 
 	return structs.ResponseGetContest{
@@ -141,6 +172,8 @@ func (c ContestsHandlerImp) GetContest(ctx *gin.Context, contestID, userID int64
 		Problems:       problems,
 		StartTime:      contest.StartTime,
 		Duration:       contest.Duration,
+		Type:           contest.Type,
+		FreezeTime:     contest.FreezeTime,
 		RegisterStatus: status,
 	}, http.StatusOK
 }
@@ -154,9 +187,9 @@ func (c ContestsHandlerImp) ListContests(ctx context.Context, req structs.Reques
 	var err error
 	var total_count int
 	if req.MyContest {
-		contests, total_count, err = c.contestsRepo.ListMyContests(ctx, req.Descending, req.Limit, req.Offset, req.Started, req.UserID, req.GetCount)
+		contests, total_count, err = c.contestsRepo.ListMyContests(ctx, req.Descending, req.Limit, req.Offset, req.Status, req.UserID, req.GetCount)
 	} else {
-		contests, total_count, err = c.contestsRepo.ListContests(ctx, req.Descending, req.Limit, req.Offset, req.Started, req.UserID, req.OwnedContest, req.GetCount)
+		contests, total_count, err = c.contestsRepo.ListContests(ctx, req.Descending, req.Limit, req.Offset, req.Status, req.UserID, req.OwnedContest, req.GetCount)
 	}
 	if err != nil {
 		logger.Error("error on listing contests: ", err)
@@ -211,8 +244,17 @@ func (c ContestsHandlerImp) UpdateContest(ctx context.Context, contestID int64,
 		}
 		return status
 	}
-	if contest.CreatedBy != ctx.Value("user_id").(int64) {
-		return http.StatusForbidden
+	if reqData.Type != "" && !slices.Contains(structs.ContestTypes, reqData.Type) {
+		logger.Warning("update contest with unknown type: ", reqData.Type)
+		return http.StatusBadRequest
+	}
+	startTime := contest.StartTime
+	if reqData.StartTime != 0 {
+		startTime = reqData.StartTime
+	}
+	if reqData.FreezeTime != 0 && reqData.FreezeTime < startTime {
+		logger.Warning("update contest with freeze time before start: ", reqData.FreezeTime)
+		return http.StatusBadRequest
 	}
 
 	err = c.contestsRepo.UpdateContests(ctx, contestID, reqData)
@@ -234,20 +276,7 @@ func (c ContestsHandlerImp) DeleteContest(ctx context.Context, contestID int64)
 		"module": "Contests",
 	})
 
-	contest, err := c.contestsRepo.GetContest(ctx, contestID)
-	if err != nil {
-		logger.Error("error on getting contest from repos: ", err)
-		status := http.StatusInternalServerError
-		if errors.Is(err, pkg.ErrNotFound) {
-			status = http.StatusNotFound
-		}
-		return status
-	}
-	if contest.CreatedBy != ctx.Value("user_id").(int64) {
-		return http.StatusForbidden
-	}
-
-	err = c.contestsRepo.DeleteContest(ctx, contestID)
+	err := c.contestsRepo.DeleteContest(ctx, contestID)
 	if err != nil {
 		logger.Error("error on deleting contest from repos: ", err)
 		status := http.StatusInternalServerError
@@ -334,46 +363,52 @@ func (c ContestsHandlerImp) GetScoreboardProblem(ctx context.Context, contestID
 func (c ContestsHandlerImp) GetContestScoreboard(ctx context.Context, req structs.RequestGetScoreboard) (ans structs.ResponseGetContestScoreboard, status int) {
 	logger := pkg.Log.WithField("method", "get_contest_scoreboard")
 
-	var err error
-	ans.Problems, err = c.GetScoreboardProblem(ctx, req.ContestID)
+	contest, err := c.contestsRepo.GetContest(ctx, req.ContestID)
 	if err != nil {
+		logger.Error("error on getting contest from repos: ", err)
 		status = http.StatusInternalServerError
+		if errors.Is(err, pkg.ErrNotFound) {
+			status = http.StatusNotFound
+		}
 		return
 	}
+	ans.Type = contest.Type
 
-	users, err := c.contestsUsersRepo.ListUsersByScore(ctx, req.ContestID, req.Limit, req.Offset)
+	ans.Problems, err = c.GetScoreboardProblem(ctx, req.ContestID)
 	if err != nil {
-		logger.Error("coudn't get contest users: ", err)
 		status = http.StatusInternalServerError
 		return
 	}
 
-	ans.Users = make([]structs.ScoreboardUserStanding, 0)
-	for i := range users {
-		var user structs.ScoreboardUserStanding
-		user.Scores = make([]int, len(ans.Problems))
-		for problemIndex, p := range ans.Problems {
-			s, err := c.submissionsRepo.GetFinalSubmission(ctx, p.ID, users[i].ID, req.ContestID)
-			if err != nil && !errors.Is(err, pkg.ErrNotFound) {
-				logger.Error("coudn't get submission from db: ", err)
-			}
-			var score int
-			if errors.Is(err, pkg.ErrNotFound) {
-				score = 0
-			} else {
-				score, err = c.judge.GetScore(ctx, s.JudgeResultID)
-				if err != nil {
-					logger.Error("coudn't get score: ", err)
-				}
-			}
-			user.Scores[problemIndex] = score
+	// the owner and the staff always see the live scoreboard
+	if isFrozen(contest) {
+		isStaff, err := c.isStaff(ctx, req.UserID, req.ContestID)
+		if err != nil {
+			logger.Error("error on checking contest staff: ", err)
+			status = http.StatusInternalServerError
+			return
 		}
-		user.UserID = users[i].ID
-		user.Username = users[i].Username
-		ans.Users = append(ans.Users, user)
+		ans.Frozen = !isStaff
+	}
+	ans.Virtual = req.Virtual
+	var count int
+	switch {
+	case req.Virtual:
+		ans.Users, count, err = c.getVirtualStandings(ctx, contest, req, ans.Problems, ans.Frozen)
+	case ans.Frozen:
+		ans.Users, err = c.getFrozenStandings(ctx, contest, req, ans.Problems)
+	default:
+		ans.Users, err = c.getStoredStandings(ctx, contest, req, ans.Problems)
+	}
+	if err != nil {
+		logger.Error("couldn't get standings: ", err)
+		status = http.StatusInternalServerError
+		return
 	}
 
-	if req.GetCount {
+	if req.GetCount && req.Virtual {
+		ans.Count = count
+	} else if req.GetCount {
 		ans.Count, err = c.contestsUsersRepo.GetContestUsersCount(ctx, req.ContestID)
 		if err != nil {
 			logger.Error("error on get contest users count: ", err)
@@ -384,17 +419,3 @@ func (c ContestsHandlerImp) GetContestScoreboard(ctx context.Context, req struct
 	status = http.StatusOK
 	return
 }
-
-func (c ContestsHandlerImp) IsContestOwner(ctx context.Context, contestID, userID int64) (bool, error) {
-	logger := pkg.Log.WithFields(logrus.Fields{
-		"method": "IsContestOwner",
-		"module": "Contests",
-	})
-
-	contest, err := c.contestsRepo.GetContest(ctx, contestID)
-	if err != nil {
-		logger.Error("error on getting contest from repos: ", err)
-		return false, err
-	}
-	return (contest.CreatedBy == userID), nil
-}
@@ -0,0 +1,100 @@
+package contests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/sirupsen/logrus"
+)
+
+// RevealSubmission shows the result of the first hidden submission of a frozen contest, in the order they are submitted.
+// the contest is unfrozen when there isn't any hidden submission left
+func (c ContestsHandlerImp) RevealSubmission(ctx context.Context, contestID int64) (structs.ResponseRevealSubmission, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "RevealSubmission",
+		"module": "Contests",
+	})
+
+	contest, status := c.getFrozenContest(ctx, logger, contestID)
+	if status != http.StatusOK {
+		return structs.ResponseRevealSubmission{}, status
+	}
+
+	submissions, _, err := c.submissionsRepo.ListSubmissions(ctx, 0, 0, contestID, false, 0, 0, false)
+	if err != nil {
+		logger.Error("error on getting contest submissions: ", err)
+		return structs.ResponseRevealSubmission{}, http.StatusInternalServerError
+	}
+	hidden := make([]structs.SubmissionMetadata, 0)
+	for _, s := range submissions {
+		if isHidden(contest, s) {
+			hidden = append(hidden, s)
+		}
+	}
+
+	if len(hidden) == 0 {
+		if err := c.contestsRepo.Unfreeze(ctx, contestID); err != nil {
+			logger.Error("error on unfreezing contest: ", err)
+			return structs.ResponseRevealSubmission{}, http.StatusInternalServerError
+		}
+		return structs.ResponseRevealSubmission{}, http.StatusNoContent
+	}
+
+	next := hidden[0]
+	if err := c.contestsRepo.RevealSubmission(ctx, contestID, next.ID); err != nil {
+		logger.Error("error on revealing submission: ", err)
+		return structs.ResponseRevealSubmission{}, http.StatusInternalServerError
+	}
+	if len(hidden) == 1 {
+		if err := c.contestsRepo.Unfreeze(ctx, contestID); err != nil {
+			logger.Error("error on unfreezing contest: ", err)
+			return structs.ResponseRevealSubmission{}, http.StatusInternalServerError
+		}
+	}
+
+	return structs.ResponseRevealSubmission{
+		SubmissionID: next.ID,
+		UserID:       next.UserID,
+		ProblemID:    next.ProblemID,
+		Score:        next.Score,
+		Remaining:    len(hidden) - 1,
+	}, http.StatusOK
+}
+
+// Unfreeze shows the results of all of the hidden submissions of a frozen contest
+func (c ContestsHandlerImp) Unfreeze(ctx context.Context, contestID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "Unfreeze",
+		"module": "Contests",
+	})
+
+	_, status := c.getFrozenContest(ctx, logger, contestID)
+	if status != http.StatusOK {
+		return status
+	}
+	if err := c.contestsRepo.Unfreeze(ctx, contestID); err != nil {
+		logger.Error("error on unfreezing contest: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// getFrozenContest gives the contest if its scoreboard is frozen
+func (c ContestsHandlerImp) getFrozenContest(ctx context.Context, logger *logrus.Entry, contestID int64) (structs.Contest, int) {
+	contest, err := c.contestsRepo.GetContest(ctx, contestID)
+	if err != nil {
+		logger.Error("error on getting contest from repos: ", err)
+		if errors.Is(err, pkg.ErrNotFound) {
+			return contest, http.StatusNotFound
+		}
+		return contest, http.StatusInternalServerError
+	}
+	if !isFrozen(contest) {
+		logger.Warning("unfreeze of a contest which is not frozen: ", contestID)
+		return contest, http.StatusBadRequest
+	}
+	return contest, http.StatusOK
+}
@@ -0,0 +1,317 @@
+package contests
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+)
+
+// icpcPenaltyMinutes is added to the penalty for each rejected attempt of a solved problem
+const icpcPenaltyMinutes = 20
+
+// getStoredStandings gives the live standings from what is kept by the judge for each problem of the users.
+// in score contests users are ranked by the sum of their best scores. in ICPC contests they are ranked by the number
+// of solved problems and then by penalty, which is the time of each accepted submission since the start plus
+// icpcPenaltyMinutes for each rejected attempt before it. a problem is solved by a submission with full score
+func (c ContestsHandlerImp) getStoredStandings(ctx context.Context, contest structs.Contest, req structs.RequestGetScoreboard, problems []structs.ScoreboardProblem) ([]structs.ScoreboardUserStanding, error) {
+	rows, err := c.contestsUsersRepo.GetStandings(ctx, req.ContestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get standings of contest")
+	}
+
+	problemIndex := make(map[int64]int, len(problems))
+	for i, p := range problems {
+		problemIndex[p.ID] = i
+	}
+
+	icpc := contest.Type == structs.ContestTypeICPC
+	standings := make([]structs.ScoreboardUserStanding, 0)
+	totals := make([]int, 0)
+	userIndex := make(map[int64]int)
+	firstSolve := make(map[int64]int64, len(problems))
+	for _, r := range rows {
+		ui, ok := userIndex[r.UserID]
+		if !ok {
+			ui = len(standings)
+			userIndex[r.UserID] = ui
+			user := structs.ScoreboardUserStanding{
+				UserID:   r.UserID,
+				Username: r.Username,
+				Scores:   make([]int, len(problems)),
+			}
+			if icpc {
+				user.Cells = make([]structs.ScoreboardCell, len(problems))
+			}
+			standings = append(standings, user)
+			totals = append(totals, 0)
+		}
+		pi, ok := problemIndex[r.ProblemID]
+		if !ok {
+			// the user has no judged submission, or the problem is removed from the contest
+			continue
+		}
+
+		user := &standings[ui]
+		user.Scores[pi] = r.Score
+		totals[ui] += r.Score
+		if !icpc {
+			continue
+		}
+		cell := &user.Cells[pi]
+		cell.Attempts = r.Attempts
+		if r.SolvedSubmissionID == 0 {
+			continue
+		}
+		cell.Solved = true
+		cell.SolvedAt = minutesSince(contest, r.SolvedTime)
+		user.Solved++
+		user.Penalty += cell.SolvedAt + icpcPenaltyMinutes*cell.Attempts
+		if first, ok := firstSolve[r.ProblemID]; !ok || r.SolvedSubmissionID < first {
+			firstSolve[r.ProblemID] = r.SolvedSubmissionID
+		}
+	}
+
+	if icpc {
+		for _, r := range rows {
+			if pi, ok := problemIndex[r.ProblemID]; ok && r.SolvedSubmissionID != 0 && firstSolve[r.ProblemID] == r.SolvedSubmissionID {
+				standings[userIndex[r.UserID]].Cells[pi].FirstSolve = true
+			}
+		}
+	}
+
+	return rankStandings(standings, totals, icpc, req), nil
+}
+
+// getFrozenStandings computes the standings from submissions of the contest the same way as getStoredStandings,
+// but submissions which are hidden by the freeze are only counted as pending. like the stored standings, it only
+// counts the submissions sent until the end
+func (c ContestsHandlerImp) getFrozenStandings(ctx context.Context, contest structs.Contest, req structs.RequestGetScoreboard, problems []structs.ScoreboardProblem) ([]structs.ScoreboardUserStanding, error) {
+	users, err := c.contestsUsersRepo.ListUsersByScore(ctx, req.ContestID, 0, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get contest users")
+	}
+	// ordered by the time of submission, so the first accepted one of a problem is its first solve
+	submissions, _, err := c.submissionsRepo.ListSubmissions(ctx, 0, 0, req.ContestID, false, 0, 0, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get contest submissions")
+	}
+
+	problemIndex := make(map[int64]int, len(problems))
+	for i, p := range problems {
+		problemIndex[p.ID] = i
+	}
+
+	standings := make([]structs.ScoreboardUserStanding, len(users))
+	totals := make([]int, len(users))
+	userIndex := make(map[int64]int, len(users))
+	for i, u := range users {
+		standings[i] = structs.ScoreboardUserStanding{
+			UserID:   u.ID,
+			Username: u.Username,
+			Scores:   make([]int, len(problems)),
+			Cells:    make([]structs.ScoreboardCell, len(problems)),
+		}
+		userIndex[u.ID] = i
+	}
+
+	icpc := contest.Type == structs.ContestTypeICPC
+	firstSolved := make(map[int64]bool, len(problems))
+	for _, s := range submissions {
+		ui, ok := userIndex[s.UserID]
+		if !ok {
+			continue
+		}
+		pi, ok := problemIndex[s.ProblemID]
+		if !ok || parseTime(s.CreatedAT) >= contest.StartTime+int64(contest.Duration) {
+			continue
+		}
+
+		user := &standings[ui]
+		cell := &user.Cells[pi]
+		if cell.Solved {
+			continue
+		}
+		if s.Status != structs.SubmissionProcessed || isHidden(contest, s) {
+			cell.Pending++
+			continue
+		}
+
+		if !applySubmission(user, &totals[ui], pi, s, minutesSince(contest, parseTime(s.CreatedAT)), icpc) {
+			continue
+		}
+		cell.FirstSolve = !firstSolved[s.ProblemID]
+		firstSolved[s.ProblemID] = true
+	}
+
+	return rankStandings(standings, totals, icpc, req), nil
+}
+
+// getVirtualStandings ranks the users of the contest together with its virtual participants, each of them by the
+// time passed from their own start. a virtual participant whose window is not over only sees what had happened
+// until the same time of the contest, others see the whole contest. hidden results of a frozen contest are
+// pending. it also gives the number of all participants
+func (c ContestsHandlerImp) getVirtualStandings(ctx context.Context, contest structs.Contest, req structs.RequestGetScoreboard, problems []structs.ScoreboardProblem, frozen bool) ([]structs.ScoreboardUserStanding, int, error) {
+	participants, err := c.contestsUsersRepo.ListParticipants(ctx, req.ContestID)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "couldn't get contest participants")
+	}
+	submissions, _, err := c.submissionsRepo.ListSubmissions(ctx, 0, 0, req.ContestID, false, 0, 0, false)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "couldn't get contest submissions")
+	}
+
+	problemIndex := make(map[int64]int, len(problems))
+	for i, p := range problems {
+		problemIndex[p.ID] = i
+	}
+
+	standings := make([]structs.ScoreboardUserStanding, len(participants))
+	totals := make([]int, len(participants))
+	userIndex := make(map[int64]int, len(participants))
+	offsets := make(map[int64]int64, len(participants))
+	for i, p := range participants {
+		standings[i] = structs.ScoreboardUserStanding{
+			UserID:   p.UserID,
+			Username: p.Username,
+			Scores:   make([]int, len(problems)),
+			Cells:    make([]structs.ScoreboardCell, len(problems)),
+			Virtual:  p.StartOffset != 0,
+		}
+		userIndex[p.UserID] = i
+		offsets[p.UserID] = p.StartOffset
+	}
+
+	elapsed := int64(contest.Duration)
+	if offset := offsets[req.UserID]; offset != 0 {
+		elapsed = min(elapsed, time.Now().Unix()-contest.StartTime-offset)
+	}
+
+	icpc := contest.Type == structs.ContestTypeICPC
+	// seconds from the start of each participant to the first solve of each problem
+	firstSolve := make(map[int64]int64, len(problems))
+	solvedAt := make(map[[2]int]int64)
+	for _, s := range submissions {
+		ui, ok := userIndex[s.UserID]
+		if !ok {
+			continue
+		}
+		pi, ok := problemIndex[s.ProblemID]
+		if !ok {
+			continue
+		}
+		since := parseTime(s.CreatedAT) - contest.StartTime - offsets[s.UserID]
+		if since < 0 || since >= elapsed {
+			continue
+		}
+
+		user := &standings[ui]
+		cell := &user.Cells[pi]
+		if cell.Solved {
+			continue
+		}
+		if s.Status != structs.SubmissionProcessed || (frozen && offsets[s.UserID] == 0 && isHidden(contest, s)) {
+			cell.Pending++
+			continue
+		}
+
+		if !applySubmission(user, &totals[ui], pi, s, int(since/60), icpc) {
+			continue
+		}
+		solvedAt[[2]int{ui, pi}] = since
+		if first, ok := firstSolve[s.ProblemID]; !ok || since < first {
+			firstSolve[s.ProblemID] = since
+		}
+	}
+	for key, since := range solvedAt {
+		if firstSolve[problems[key[1]].ID] == since {
+			standings[key[0]].Cells[key[1]].FirstSolve = true
+		}
+	}
+
+	return rankStandings(standings, totals, icpc, req), len(participants), nil
+}
+
+// applySubmission counts a judged submission in the standing of its user, solvedAt is the minutes from the start
+// of the user to the submission. it tells whether the submission solves the problem
+func applySubmission(user *structs.ScoreboardUserStanding, total *int, pi int, s structs.SubmissionMetadata, solvedAt int, icpc bool) bool {
+	cell := &user.Cells[pi]
+	if s.Score > user.Scores[pi] {
+		*total += s.Score - user.Scores[pi]
+		user.Scores[pi] = s.Score
+	}
+	if s.Score < 100 {
+		cell.Attempts++
+		return false
+	}
+
+	cell.Solved = true
+	cell.SolvedAt = solvedAt
+	if icpc {
+		user.Solved++
+		user.Penalty += solvedAt + icpcPenaltyMinutes*cell.Attempts
+	}
+	return true
+}
+
+// rankStandings sorts the standings by the rule of the contest and gives the requested page of it,
+// totals are the sum of scores of each user
+func rankStandings(standings []structs.ScoreboardUserStanding, totals []int, icpc bool, req structs.RequestGetScoreboard) []structs.ScoreboardUserStanding {
+	order := make([]int, len(standings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if !icpc {
+			return totals[a] > totals[b]
+		}
+		if standings[a].Solved != standings[b].Solved {
+			return standings[a].Solved > standings[b].Solved
+		}
+		return standings[a].Penalty < standings[b].Penalty
+	})
+
+	if req.Offset >= len(order) {
+		return make([]structs.ScoreboardUserStanding, 0)
+	}
+	order = order[req.Offset:]
+	if req.Limit != 0 && req.Limit < len(order) {
+		order = order[:req.Limit]
+	}
+	ans := make([]structs.ScoreboardUserStanding, len(order))
+	for i, ind := range order {
+		ans[i] = standings[ind]
+	}
+	return ans
+}
+
+// isFrozen tells whether the scoreboard is frozen for users other than the owner
+func isFrozen(contest structs.Contest) bool {
+	return contest.FreezeTime != 0 && !contest.Unfrozen && time.Now().Unix() >= contest.FreezeTime
+}
+
+// isHidden tells whether the result of the submission is hidden by the freeze
+func isHidden(contest structs.Contest, s structs.SubmissionMetadata) bool {
+	return parseTime(s.CreatedAT) >= contest.FreezeTime && s.ID > contest.RevealedUntil
+}
+
+// parseTime gives the unix time of a submission time
+func parseTime(createdAt string) int64 {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// minutesSince gives the minutes passed from start of the contest to t
+func minutesSince(contest structs.Contest, t int64) int {
+	minutes := (t - contest.StartTime) / 60
+	if minutes < 0 {
+		return 0
+	}
+	return int(minutes)
+}
@@ -2,15 +2,61 @@ package problems
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/structs"
 	"github.com/pkg/errors"
 	"io"
+	"path"
+	"slices"
+	"sort"
 	"strings"
 )
 
 const inKeyword = "in"
 const outKeyword = "out"
+const groupsManifest = "groups.json"
+
+// limits of a problem, time in milliseconds and memory in megabytes
+const (
+	defaultTimeLimit   = 1000
+	defaultMemoryLimit = 256
+	maxTimeLimit       = 10000
+	maxMemoryLimit     = 1024
+)
+
+func validLimits(timeLimit, memoryLimit int64) bool {
+	return timeLimit >= 0 && timeLimit <= maxTimeLimit && memoryLimit >= 0 && memoryLimit <= maxMemoryLimit
+}
+
+// validateChecker checks the request and drops the fields which are not used by its checker type
+func validateChecker(req structs.RequestSetChecker) (structs.Checker, error) {
+	checker := structs.Checker{Type: req.Type}
+	if checker.Type == "" {
+		checker.Type = structs.CheckerExact
+	}
+	if !slices.Contains(structs.CheckerTypes, checker.Type) {
+		return structs.Checker{}, errors.Errorf("unknown checker type %v", req.Type)
+	}
+
+	switch checker.Type {
+	case structs.CheckerFloat:
+		if req.Epsilon < 0 {
+			return structs.Checker{}, errors.New("epsilon can't be negative")
+		}
+		checker.Epsilon = req.Epsilon
+	case structs.CheckerCustom, structs.CheckerInteractive:
+		if !slices.Contains(structs.SupportedLanguages, req.Language) {
+			return structs.Checker{}, errors.Errorf("language %v is not supported", req.Language)
+		}
+		if req.Code == "" {
+			return structs.Checker{}, errors.New("checker code is empty")
+		}
+		checker.Language = req.Language
+		checker.Code = req.Code
+	}
+	return checker, nil
+}
 
 func getDirsName(r *zip.Reader) (in string, out string, err error) {
 
@@ -35,52 +81,123 @@ func getDirsName(r *zip.Reader) (in string, out string, err error) {
 	}
 	return
 }
-func unzip(data io.ReaderAt, size int64) ([]structs.Testcase, error) {
+
+// testGroupsManifest is the optional groups.json file in the testcases zip, tests are named by their path in the in directory
+// and every test must be in a group. a zip with the manifest replaces all of the tests of the problem
+//
+//	{"groups": [{"id": 1, "points": 40, "tests": ["1", "2"]}, {"id": 2, "points": 60, "tests": ["3"]}]}
+type testGroupsManifest struct {
+	Groups []struct {
+		ID     int64    `json:"id"`
+		Points int      `json:"points"`
+		Tests  []string `json:"tests"`
+	} `json:"groups"`
+}
+
+func readFile(f *zip.File) (string, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return "", errors.WithStack(errors.WithMessage(err, "error on open file"))
+	}
+	defer reader.Close()
+	dataRaw, err := io.ReadAll(reader)
+	if err != nil {
+		return "", errors.WithStack(errors.WithMessage(err, "error on read file"))
+	}
+	return strings.TrimSpace(string(dataRaw)), nil
+}
+
+// unzip reads the testcases and the test groups declared in groups.json, groups are nil if there is no manifest
+func unzip(data io.ReaderAt, size int64) ([]structs.Testcase, []structs.TestGroup, error) {
 
 	r, err := zip.NewReader(data, size)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	in, out, err := getDirsName(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	var manifest *testGroupsManifest
 	testCases := make(map[string]structs.Testcase)
 	for _, f := range r.File {
 		if f.FileInfo().IsDir() {
 			continue
 		}
-		reader, err := f.OpenRaw()
-		if err != nil {
-			return nil, errors.WithStack(errors.WithMessage(err, "error on open raw file"))
-		}
-		dataRaw, err := io.ReadAll(reader)
-		data := strings.TrimSpace(string(dataRaw))
+		data, err := readFile(f)
 		if err != nil {
-			return nil, errors.WithStack(errors.WithMessage(err, "error on read file"))
+			return nil, nil, err
 		}
 
 		if strings.HasPrefix(f.Name, in) {
-			testName := strings.Replace(f.Name, inKeyword, "", -1)
+			testName := strings.TrimPrefix(f.Name, in)
 			t := testCases[testName]
 			t.Input = data
 			testCases[testName] = t
 		}
 		if strings.HasPrefix(f.Name, out) {
-			testName := strings.Replace(f.Name, outKeyword, "", -1)
+			testName := strings.TrimPrefix(f.Name, out)
 			t := testCases[testName]
 			t.ExpectedOutput = data
 			testCases[testName] = t
 		}
+		if path.Base(f.Name) == groupsManifest {
+			manifest = &testGroupsManifest{}
+			if err := json.Unmarshal([]byte(data), manifest); err != nil {
+				return nil, nil, errors.WithMessage(pkg.ErrBadRequest, "invalid groups manifest: "+err.Error())
+			}
+		}
+	}
+
+	var groups []structs.TestGroup
+	if manifest != nil {
+		groups = make([]structs.TestGroup, 0, len(manifest.Groups))
+		totalPoints := 0
+		for _, g := range manifest.Groups {
+			// an empty group is never passed, so the problem couldn't be solved
+			if g.ID <= 0 || g.Points < 0 || len(g.Tests) == 0 || slices.ContainsFunc(groups, func(e structs.TestGroup) bool { return e.ID == g.ID }) {
+				return nil, nil, errors.WithMessagef(pkg.ErrBadRequest, "invalid group %v", g.ID)
+			}
+			totalPoints += g.Points
+			groups = append(groups, structs.TestGroup{ID: g.ID, Points: g.Points})
+			for _, name := range g.Tests {
+				t, exists := testCases[name]
+				if !exists || t.GroupID != 0 {
+					return nil, nil, errors.WithMessagef(pkg.ErrBadRequest, "test %v of group %v doesn't exist or is in another group", name, g.ID)
+				}
+				t.GroupID = g.ID
+				testCases[name] = t
+			}
+		}
+		if totalPoints == 0 {
+			return nil, nil, errors.WithMessage(pkg.ErrBadRequest, "groups give no points")
+		}
+		// tests out of groups give no points, so they are most likely forgotten in the manifest
+		for name, t := range testCases {
+			if t.GroupID == 0 {
+				return nil, nil, errors.WithMessagef(pkg.ErrBadRequest, "test %v isn't in any group", name)
+			}
+		}
+	}
+
+	// tests of a group are kept together so the runner can skip the rest of a group after a failure
+	names := make([]string, 0, len(testCases))
+	for name := range testCases {
+		names = append(names, name)
 	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := testCases[names[i]], testCases[names[j]]
+		if a.GroupID != b.GroupID {
+			return a.GroupID < b.GroupID
+		}
+		return names[i] < names[j]
+	})
 
-	ans := make([]structs.Testcase, len(testCases))
-	i := 0
-	for _, v := range testCases {
-		ans[i] = v
-		i++
+	ans := make([]structs.Testcase, len(names))
+	for i, name := range names {
+		ans[i] = testCases[name]
 	}
-	return ans, nil
+	return ans, groups, nil
 }
@@ -21,27 +21,43 @@ type ProblemsHandler interface {
 	AddTestcase(ctx context.Context, problemID int64, data []byte) int
 	GetTestcase(ctx context.Context, problemID int64) ([]structs.ResponseGetTestcase, int)
 	UpdateProblem(ctx context.Context, req structs.RequestUpdateProblem) int
+	SetChecker(ctx context.Context, req structs.RequestSetChecker) int
+	ListAccess(ctx context.Context, problemID int64) ([]structs.ProblemAccess, int)
+	GrantAccess(ctx context.Context, problemID int64, req structs.RequestGrantProblemAccess) int
+	RevokeAccess(ctx context.Context, problemID, userID int64) int
 }
 
 type ProblemsHandlerImp struct {
 	problemMetadataRepo     repos.ProblemsMetadataRepo
 	problemsDescriptionRepo repos.ProblemDescriptionsRepo
 	testcaseRepo            repos.TestCaseRepo
+	usersRepo               repos.UsersRepo
 }
 
 func NewProblemsHandler(
 	problemsRepo repos.ProblemsMetadataRepo, problemsDescriptionRepo repos.ProblemDescriptionsRepo,
-	testcaseRepo repos.TestCaseRepo,
+	testcaseRepo repos.TestCaseRepo, usersRepo repos.UsersRepo,
 ) ProblemsHandler {
 	return &ProblemsHandlerImp{
 		problemMetadataRepo:     problemsRepo,
 		problemsDescriptionRepo: problemsDescriptionRepo,
 		testcaseRepo:            testcaseRepo,
+		usersRepo:               usersRepo,
 	}
 }
 
 func (p ProblemsHandlerImp) CreateProblem(ctx context.Context, req structs.RequestCreateProblem) (ans structs.ResponseCreateProblem, status int) {
 	logger := pkg.Log.WithField("method", "create_problem")
+	if !validLimits(req.TimeLimit, req.MemoryLimit) {
+		status = http.StatusBadRequest
+		return
+	}
+	if req.TimeLimit == 0 {
+		req.TimeLimit = defaultTimeLimit
+	}
+	if req.MemoryLimit == 0 {
+		req.MemoryLimit = defaultMemoryLimit
+	}
 	docID, err := p.problemsDescriptionRepo.Insert(req.Description, nil)
 	if err != nil {
 		logger.Error("error on inserting problem description: ", err)
@@ -49,11 +65,13 @@ func (p ProblemsHandlerImp) CreateProblem(ctx context.Context, req structs.Reque
 		return
 	}
 	problem := structs.Problem{
-		Title:      req.Title,
-		DocumentID: docID,
-		CreatedBy:  ctx.Value("user_id").(int64),
-		IsPrivate:  req.IsPrivate,
-		Hardness:   req.Hardness,
+		Title:       req.Title,
+		DocumentID:  docID,
+		CreatedBy:   ctx.Value("user_id").(int64),
+		IsPrivate:   req.IsPrivate,
+		Hardness:    req.Hardness,
+		TimeLimit:   req.TimeLimit,
+		MemoryLimit: req.MemoryLimit,
 	}
 	ans.ProblemID, err = p.problemMetadataRepo.InsertProblem(ctx, problem)
 	if err != nil {
@@ -94,6 +112,10 @@ func (p ProblemsHandlerImp) GetProblem(ctx context.Context, problemID int64) (st
 		Hardness:    problem.Hardness,
 		Description: doc.Description,
 		IsOwned:     problem.CreatedBy == ctx.Value("user_id").(int64),
+		IsPrivate:   problem.IsPrivate,
+		TimeLimit:   problem.TimeLimit,
+		MemoryLimit: problem.MemoryLimit,
+		Checker:     problem.CheckerType,
 	}, http.StatusOK
 }
 
@@ -102,7 +124,8 @@ func (p ProblemsHandlerImp) ListProblem(ctx context.Context, req structs.Request
 		"method": "ListProblem",
 		"module": "Problems",
 	})
-	problems, total_count, err := p.problemMetadataRepo.ListProblems(ctx, req.OrderedBy, req.Descending, req.Limit, req.Offset, req.GetCount)
+	userID := ctx.Value("user_id").(int64)
+	problems, total_count, err := p.problemMetadataRepo.ListProblems(ctx, req.OrderedBy, req.Descending, req.Limit, req.Offset, userID, req.GetCount)
 	if err != nil {
 		logger.Error("error on listing problems: ", err)
 		return structs.ResponseListProblems{}, http.StatusInternalServerError
@@ -129,6 +152,9 @@ func (p ProblemsHandlerImp) UpdateProblem(ctx context.Context, req structs.Reque
 		"method": "UpdateProblem",
 		"module": "Problems",
 	})
+	if !validLimits(req.TimeLimit, req.MemoryLimit) {
+		return http.StatusBadRequest
+	}
 
 	problem, err := p.problemMetadataRepo.GetProblem(ctx, req.Id)
 	if err != nil {
@@ -139,11 +165,8 @@ func (p ProblemsHandlerImp) UpdateProblem(ctx context.Context, req structs.Reque
 		}
 		return status
 	}
-	if problem.CreatedBy != ctx.Value("user_id").(int64) {
-		return http.StatusForbidden
-	}
 
-	err = p.problemMetadataRepo.UpdateProblem(ctx, req.Id, req.Title, req.Hardness)
+	err = p.problemMetadataRepo.UpdateProblem(ctx, req.Id, req.Title, req.Hardness, req.TimeLimit, req.MemoryLimit)
 	if err != nil {
 		logger.Error("error on updating problem on problem metadata repo: ", err)
 		status := http.StatusInternalServerError
@@ -165,24 +188,31 @@ func (p ProblemsHandlerImp) UpdateProblem(ctx context.Context, req structs.Reque
 	return http.StatusAccepted
 }
 
-func (p ProblemsHandlerImp) DeleteProblem(ctx context.Context, problemID int64) int {
+func (p ProblemsHandlerImp) SetChecker(ctx context.Context, req structs.RequestSetChecker) int {
 	logger := pkg.Log.WithFields(logrus.Fields{
-		"method": "DeleteProblem",
+		"method": "SetChecker",
 		"module": "Problems",
 	})
 
-	problem, err := p.problemMetadataRepo.GetProblem(ctx, problemID)
+	checker, err := validateChecker(req)
 	if err != nil {
-		logger.Error("error on getting problem from problem metadata repos: ", err)
-		status := http.StatusInternalServerError
-		if errors.Is(err, pkg.ErrNotFound) {
-			status = http.StatusNotFound
-		}
-		return status
+		logger.Warning("invalid checker: ", err)
+		return http.StatusBadRequest
 	}
-	if problem.CreatedBy != ctx.Value("user_id").(int64) {
-		return http.StatusForbidden
+
+	err = p.problemMetadataRepo.UpdateChecker(ctx, req.ProblemID, checker)
+	if err != nil {
+		logger.Error("error on updating checker on problem metadata repo: ", err)
+		return http.StatusInternalServerError
 	}
+	return http.StatusAccepted
+}
+
+func (p ProblemsHandlerImp) DeleteProblem(ctx context.Context, problemID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "DeleteProblem",
+		"module": "Problems",
+	})
 
 	documentID, err := p.problemMetadataRepo.DeleteProblem(ctx, problemID)
 	if err != nil {
@@ -209,12 +239,35 @@ func (p ProblemsHandlerImp) AddTestcase(ctx context.Context, problemID int64, da
 		"module": "Problems",
 	})
 
-	testCases, err := unzip(bytes.NewReader(data), int64(len(data)))
+	testCases, groups, err := unzip(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		logger.Error("error on unzip file: ", err)
+		if errors.Is(err, pkg.ErrBadRequest) {
+			return http.StatusBadRequest
+		}
 		return http.StatusInternalServerError
 	}
 
+	if groups != nil {
+		// groups belong to the whole problem, so a manifest comes with all of the tests and replaces the old ones
+		err = p.testcaseRepo.ReplaceTests(ctx, problemID, testCases, groups)
+		if err != nil {
+			logger.Error("error on replacing testcases of problem: ", err)
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}
+
+	// tests out of groups give no points in a problem with groups
+	oldGroups, err := p.testcaseRepo.GetGroups(ctx, problemID)
+	if err != nil {
+		logger.Error("error on getting test groups of problem: ", err)
+		return http.StatusInternalServerError
+	}
+	if len(oldGroups) != 0 {
+		logger.Warning("testcases without groups for a problem with groups, problem id: ", problemID)
+		return http.StatusBadRequest
+	}
 	for _, t := range testCases {
 		t.ProblemID = problemID
 		_, err := p.testcaseRepo.Insert(ctx, t)
@@ -241,11 +294,60 @@ func (p ProblemsHandlerImp) GetTestcase(ctx context.Context, problemID int64) ([
 	ans := make([]structs.ResponseGetTestcase, len(testCases))
 	for i, t := range testCases {
 		ans[i] = structs.ResponseGetTestcase{
-			ID:     t.ID,
-			Input:  t.Input,
-			Output: t.ExpectedOutput,
+			ID:      t.ID,
+			Input:   t.Input,
+			Output:  t.ExpectedOutput,
+			GroupID: t.GroupID,
 		}
 	}
 
 	return ans, http.StatusOK
 }
+
+func (p ProblemsHandlerImp) ListAccess(ctx context.Context, problemID int64) ([]structs.ProblemAccess, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "ListAccess",
+		"module": "Problems",
+	})
+
+	access, err := p.problemMetadataRepo.ListAccess(ctx, problemID)
+	if err != nil {
+		logger.Error("error on getting access of problem from db: ", err)
+		return nil, http.StatusInternalServerError
+	}
+	return access, http.StatusOK
+}
+
+// GrantAccess shares the problem with the user, it is only needed for private problems
+func (p ProblemsHandlerImp) GrantAccess(ctx context.Context, problemID int64, req structs.RequestGrantProblemAccess) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "GrantAccess",
+		"module": "Problems",
+	})
+
+	if _, err := p.usersRepo.GetByID(ctx, req.UserID); err != nil {
+		logger.Warningf("grant access to unknown user %v: %v", req.UserID, err)
+		return http.StatusNotFound
+	}
+
+	err := p.problemMetadataRepo.GrantAccess(ctx, problemID, req.UserID)
+	if err != nil {
+		logger.Error("error on insert access to db: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+func (p ProblemsHandlerImp) RevokeAccess(ctx context.Context, problemID, userID int64) int {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "RevokeAccess",
+		"module": "Problems",
+	})
+
+	err := p.problemMetadataRepo.RevokeAccess(ctx, problemID, userID)
+	if err != nil {
+		logger.Error("error on delete access from db: ", err)
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
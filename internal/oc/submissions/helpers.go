@@ -25,3 +25,21 @@ func calcScore(results []structs.TestResult, userError string) int {
 	}
 	return 100 * accepeted / len(results)
 }
+
+// statusMessage describes a submission which is not judged yet
+func statusMessage(submission structs.SubmissionMetadata) string {
+	switch submission.Status {
+	case structs.SubmissionQueued:
+		return "In queue"
+	case structs.SubmissionCompiling:
+		return "Compiling"
+	case structs.SubmissionRunning:
+		return fmt.Sprintf("Running on test %d of %d", submission.TestsDone+1, submission.TestsTotal)
+	}
+	return "Not judged yet"
+}
+
+// isJudged tells whether the status is final and no more progress is reported for the submission
+func isJudged(status string) bool {
+	return status == structs.SubmissionProcessed || status == structs.SubmissionJudgeError
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/ocontest/backend/internal/authz"
 	"github.com/ocontest/backend/internal/db/repos"
 	"github.com/ocontest/backend/internal/judge"
 	"github.com/ocontest/backend/internal/minio"
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/structs"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,6 +22,9 @@ type Handler interface {
 	Submit(ctx context.Context, request structs.RequestSubmit) (submissionID int64, status int)
 	Get(ctx context.Context, userID, submissionID int64) (structs.ResponseGetSubmission, string, int)
 	GetResults(ctx context.Context, submissionID int64) (structs.ResponseGetSubmissionResults, int)
+	Watch(ctx context.Context, userID, submissionID int64) (<-chan structs.JudgeProgress, int)
+	Rejudge(ctx context.Context, req structs.RequestRejudge) (structs.Rejudge, int)
+	GetRejudge(ctx context.Context, userID, rejudgeID int64) (structs.Rejudge, int)
 	ListSubmission(ctx context.Context, req structs.RequestListSubmissions) (structs.ResponseListSubmissions, int)
 }
 
@@ -28,16 +35,20 @@ type SubmissionsHandlerImp struct {
 	contestsUsersRepo      repos.ContestsUsersRepo
 	contestsMetadataRepo   repos.ContestsMetadataRepo
 	contestsProblemsRepo   repos.ContestsProblemsRepo
+	problemsMetadataRepo   repos.ProblemsMetadataRepo
+	authorizer             authz.Authorizer
 }
 
-func NewSubmissionsHandler(submissionRepo repos.SubmissionMetadataRepo, contestRepo repos.ContestsMetadataRepo, contestsProblemsRepo repos.ContestsProblemsRepo, contestsUsersRepo repos.ContestsUsersRepo, minioHandler minio.MinioHandler, judgeHandler judge.Judge) Handler {
+func NewSubmissionsHandler(submissionRepo repos.SubmissionMetadataRepo, contestRepo repos.ContestsMetadataRepo, contestsProblemsRepo repos.ContestsProblemsRepo, contestsUsersRepo repos.ContestsUsersRepo, problemsMetadataRepo repos.ProblemsMetadataRepo, minioHandler minio.MinioHandler, judgeHandler judge.Judge, authorizer authz.Authorizer) Handler {
 	return &SubmissionsHandlerImp{
 		submissionMetadataRepo: submissionRepo,
+		problemsMetadataRepo:   problemsMetadataRepo,
 		minioHandler:           minioHandler,
 		judge:                  judgeHandler,
 		contestsUsersRepo:      contestsUsersRepo,
 		contestsMetadataRepo:   contestRepo,
 		contestsProblemsRepo:   contestsProblemsRepo,
+		authorizer:             authorizer,
 	}
 }
 
@@ -49,13 +60,25 @@ func (s *SubmissionsHandlerImp) Submit(ctx context.Context, request structs.Requ
 
 	status = http.StatusInternalServerError
 
+	if !slices.Contains(structs.SupportedLanguages, request.Language) {
+		logger.Warning("submit with unsupported language: ", request.Language)
+		status = http.StatusBadRequest
+		return
+	}
+
 	if request.ContestID != 0 {
+		isStaff, err := s.isContestStaff(ctx, request.UserID, request.ContestID)
+		if err != nil {
+			logger.Error("error on checking contest staff: ", err)
+			return
+		}
+
 		isReg, err := s.contestsUsersRepo.IsRegistered(ctx, request.ContestID, request.UserID)
 		if err != nil {
 			logger.Error("error on check to contests users repo: ", err)
 			return
 		}
-		if !isReg {
+		if !isReg && !isStaff {
 			logger.Warningf("forbidden contest submit, user id: %v, contest id: %v", request.UserID, request.ContestID)
 			status = http.StatusForbidden
 			return
@@ -66,12 +89,31 @@ func (s *SubmissionsHandlerImp) Submit(ctx context.Context, request structs.Requ
 			logger.Error("error on check to contests metadata repo: ", err)
 			return
 		}
-		if !started {
+		if !started && !isStaff {
 			logger.Warningf("early contest submit, user id: %v, contest id: %v", request.UserID, request.ContestID)
 			status = http.StatusForbidden
 			return
 		}
 
+		ended, err := s.contestsMetadataRepo.HasEnded(ctx, request.ContestID)
+		if err != nil {
+			logger.Error("error on check to contests metadata repo: ", err)
+			return
+		}
+		if ended {
+			inVirtual, err := s.isInVirtualWindow(ctx, request.ContestID, request.UserID)
+			if err != nil {
+				logger.Error("error on checking virtual participation: ", err)
+				return
+			}
+			if !inVirtual {
+				// problems of a finished contest can still be submitted out of the contest
+				logger.Warningf("late contest submit, user id: %v, contest id: %v", request.UserID, request.ContestID)
+				status = http.StatusForbidden
+				return
+			}
+		}
+
 		validProblem, err := s.contestsProblemsRepo.HasProblem(ctx, request.ContestID, request.ProblemID)
 		if err != nil {
 			logger.Error("error on check to contests problems repo: ", err)
@@ -82,6 +124,18 @@ func (s *SubmissionsHandlerImp) Submit(ctx context.Context, request structs.Requ
 			status = http.StatusNotFound
 			return
 		}
+	} else {
+		err := s.authorizer.CanViewProblem(ctx, request.UserID, request.ProblemID)
+		if err != nil {
+			logger.Warningf("submit to unseen problem, user id: %v, problem id: %v: %v", request.UserID, request.ProblemID, err)
+			switch {
+			case errors.Is(err, pkg.ErrForbidden):
+				status = http.StatusForbidden
+			case errors.Is(err, pkg.ErrNotFound):
+				status = http.StatusNotFound
+			}
+			return
+		}
 	}
 
 	submission := structs.SubmissionMetadata{
@@ -105,17 +159,47 @@ func (s *SubmissionsHandlerImp) Submit(ctx context.Context, request structs.Requ
 		return submissionID, http.StatusInternalServerError
 	}
 
-	go func() {
-		err = s.judge.Dispatch(context.Background(), submissionID, request.ContestID) // ctx must not be passed to judge, because deadlines are different
-		if err != nil {
-			logger.Error("error on dispatching judge: ", err)
-			return
-		}
-	}()
+	err = s.judge.Dispatch(ctx, submissionID)
+	if err != nil {
+		logger.Error("error on dispatching judge: ", err)
+		return submissionID, http.StatusInternalServerError
+	}
 
 	return submissionID, http.StatusOK
 }
 
+// isContestStaff tells whether the user can test the contest, the staff can submit without registration
+// and before the start
+func (s *SubmissionsHandlerImp) isContestStaff(ctx context.Context, userID, contestID int64) (bool, error) {
+	err := s.authorizer.CanTestContest(ctx, userID, contestID)
+	if errors.Is(err, pkg.ErrForbidden) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// isInVirtualWindow tells whether the user is a virtual participant of the finished contest whose
+// own duration of the contest is not over yet
+func (s *SubmissionsHandlerImp) isInVirtualWindow(ctx context.Context, contestID, userID int64) (bool, error) {
+	offset, err := s.contestsUsersRepo.GetStartOffset(ctx, contestID, userID)
+	if errors.Is(err, pkg.ErrNotFound) {
+		// the user never joined the contest, not even virtually
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithMessage(err, "couldn't get start offset")
+	}
+	if offset == 0 {
+		return false, nil
+	}
+
+	contest, err := s.contestsMetadataRepo.GetContest(ctx, contestID)
+	if err != nil {
+		return false, errors.WithMessage(err, "couldn't get contest")
+	}
+	return time.Now().Unix() < contest.StartTime+offset+int64(contest.Duration), nil
+}
+
 func (s *SubmissionsHandlerImp) Get(ctx context.Context, userID, submissionID int64) (ans structs.ResponseGetSubmission, contentType string, status int) {
 	logger := pkg.Log.WithFields(logrus.Fields{
 		"method": "GetByID",
@@ -166,10 +250,18 @@ func (s *SubmissionsHandlerImp) GetResults(ctx context.Context, submissionID int
 		return
 	}
 
-	if submission.Status != "processed" {
-		ans.Verdicts = nil
-		ans.ServiceMessage = "not judged yet!"
-		status = http.StatusTooEarly
+	ans.Status = submission.Status
+	switch submission.Status {
+	case structs.SubmissionProcessed:
+	case structs.SubmissionJudgeError:
+		ans.ServiceMessage = "Judge failed: " + submission.StatusMessage
+		status = http.StatusOK
+		return
+	default:
+		ans.TestsDone = submission.TestsDone
+		ans.TestsTotal = submission.TestsTotal
+		ans.ServiceMessage = statusMessage(submission)
+		status = http.StatusOK
 		return
 	}
 
@@ -188,6 +280,7 @@ func (s *SubmissionsHandlerImp) GetResults(ctx context.Context, submissionID int
 		}, http.StatusInternalServerError
 	}
 	ans = structs.ResponseGetSubmissionResults{
+		Status:         submission.Status,
 		Verdicts:       make([]structs.Verdict, 0),
 		ServiceMessage: `All tests ran successfully`,
 	}
@@ -209,6 +302,128 @@ func (s *SubmissionsHandlerImp) GetResults(ctx context.Context, submissionID int
 	return
 }
 
+// Watch gives the current status of the submission followed by the progress of judging it,
+// the channel is closed after the final report or when ctx is done. like Get, only public submissions can be
+// watched by other users
+func (s *SubmissionsHandlerImp) Watch(ctx context.Context, userID, submissionID int64) (<-chan structs.JudgeProgress, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "Watch",
+		"module": "Submissions",
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	// watching is started before reading the status, so no report is missed in between
+	events := s.judge.Watch(ctx, submissionID)
+
+	submission, err := s.submissionMetadataRepo.Get(ctx, submissionID)
+	if err != nil {
+		cancel()
+		if errors.Is(err, pkg.ErrNotFound) {
+			return nil, http.StatusNotFound
+		}
+		logger.Error("error on getting submission from db: ", err)
+		return nil, http.StatusInternalServerError
+	}
+	if !submission.Public && submission.UserID != userID {
+		cancel()
+		logger.Warningf("forbidden submission watch, user id: %v, owner id: %v", userID, submission.UserID)
+		return nil, http.StatusForbidden
+	}
+
+	current := structs.JudgeProgress{
+		SubmissionID: submissionID,
+		Status:       submission.Status,
+		TestsDone:    submission.TestsDone,
+		TestsTotal:   submission.TestsTotal,
+	}
+	switch submission.Status {
+	case structs.SubmissionProcessed:
+		current.Score = submission.Score
+	case structs.SubmissionJudgeError:
+		current.Message = submission.StatusMessage
+	}
+
+	ans := make(chan structs.JudgeProgress)
+	go func() {
+		defer cancel()
+		defer close(ans)
+
+		progress, ok := current, true
+		for ok {
+			select {
+			case ans <- progress:
+			case <-ctx.Done():
+				return
+			}
+			if isJudged(progress.Status) {
+				return
+			}
+			progress, ok = <-events
+		}
+	}()
+	return ans, http.StatusOK
+}
+
+// Rejudge judges the judged submissions of the request again with the current tests of their problems.
+// submissions are dispatched in the background, the returned rejudge can be used to follow its progress.
+// the user should be checked to be able to edit what is rejudged before
+func (s *SubmissionsHandlerImp) Rejudge(ctx context.Context, req structs.RequestRejudge) (structs.Rejudge, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "Rejudge",
+		"module": "Submissions",
+	})
+
+	rejudge := structs.Rejudge{
+		UserID:       req.UserID,
+		ProblemID:    req.ProblemID,
+		ContestID:    req.ContestID,
+		SubmissionID: req.SubmissionID,
+	}
+	rejudgeID, submissionIDs, err := s.submissionMetadataRepo.InsertRejudge(ctx, rejudge)
+	if err != nil {
+		logger.Error("error on inserting rejudge: ", err)
+		return structs.Rejudge{}, http.StatusInternalServerError
+	}
+	logger.Infof("rejudge %v started by user %v with %v submissions", rejudgeID, req.UserID, len(submissionIDs))
+
+	// request may be done before all of the submissions are dispatched
+	go func() {
+		for _, id := range submissionIDs {
+			if err := s.judge.Dispatch(context.Background(), id); err != nil {
+				logger.Errorf("error on dispatching submission %v of rejudge %v: %v", id, rejudgeID, err)
+			}
+		}
+	}()
+
+	rejudge, err = s.submissionMetadataRepo.GetRejudge(ctx, rejudgeID)
+	if err != nil {
+		logger.Error("error on getting rejudge: ", err)
+		return structs.Rejudge{}, http.StatusInternalServerError
+	}
+	return rejudge, http.StatusOK
+}
+
+func (s *SubmissionsHandlerImp) GetRejudge(ctx context.Context, userID, rejudgeID int64) (structs.Rejudge, int) {
+	logger := pkg.Log.WithFields(logrus.Fields{
+		"method": "GetRejudge",
+		"module": "Submissions",
+	})
+
+	rejudge, err := s.submissionMetadataRepo.GetRejudge(ctx, rejudgeID)
+	if err != nil {
+		if errors.Is(err, pkg.ErrNotFound) {
+			return structs.Rejudge{}, http.StatusNotFound
+		}
+		logger.Error("error on getting rejudge: ", err)
+		return structs.Rejudge{}, http.StatusInternalServerError
+	}
+	if rejudge.UserID != userID {
+		logger.Warningf("forbidden rejudge access, user id: %v, owner id: %v", userID, rejudge.UserID)
+		return structs.Rejudge{}, http.StatusForbidden
+	}
+	return rejudge, http.StatusOK
+}
+
 func (s *SubmissionsHandlerImp) ListSubmission(ctx context.Context, req structs.RequestListSubmissions) (structs.ResponseListSubmissions, int) {
 	logger := pkg.Log.WithFields(logrus.Fields{
 		"method": "ListSubmission",
@@ -0,0 +1,93 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+)
+
+// github doesn't support openid connect for users, so the user and its emails are read from its api.
+// the endpoints can be changed by the issuer of the config for github enterprise, like https://github.example.com
+type githubProvider struct {
+	conf          configs.SectionOIDCProvider
+	redirectURL   string
+	client        *http.Client
+	authEndpoint  string
+	tokenEndpoint string
+	apiURL        string
+}
+
+func newGithubProvider(conf configs.SectionOIDCProvider, redirectURL string, client *http.Client) *githubProvider {
+	if conf.Scopes == "" {
+		conf.Scopes = "read:user user:email"
+	}
+	p := &githubProvider{
+		conf:          conf,
+		redirectURL:   redirectURL,
+		client:        client,
+		authEndpoint:  "https://github.com/login/oauth/authorize",
+		tokenEndpoint: "https://github.com/login/oauth/access_token",
+		apiURL:        "https://api.github.com",
+	}
+	if conf.Issuer != "" {
+		issuer := strings.TrimSuffix(conf.Issuer, "/")
+		p.authEndpoint = issuer + "/login/oauth/authorize"
+		p.tokenEndpoint = issuer + "/login/oauth/access_token"
+		p.apiURL = issuer + "/api/v3"
+	}
+	return p
+}
+
+func (p *githubProvider) AuthURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	return authURL(p.authEndpoint, url.Values{
+		"client_id":             {p.conf.ClientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {p.conf.Scopes},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	})
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error) {
+	token, err := exchangeCode(ctx, p.client, p.tokenEndpoint, p.conf, p.redirectURL, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := getJSON(ctx, p.client, p.apiURL+"/user", token.AccessToken, &user); err != nil {
+		return Identity{}, err
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.client, p.apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return Identity{}, err
+	}
+	if user.ID == 0 {
+		return Identity{}, fmt.Errorf("github gave no user id: %w", pkg.ErrForbidden)
+	}
+
+	identity := Identity{
+		Subject:  fmt.Sprintf("%d", user.ID),
+		Username: user.Login,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+		}
+	}
+	return identity, nil
+}
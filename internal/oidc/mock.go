@@ -0,0 +1,239 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockKeyID is the kid of the only key of MockProvider
+const mockKeyID = "mock"
+
+// MockProvider is a minimal openid connect provider for trying the login without a real provider. its login
+// page logs in any email without a password, so it must never be used in production
+type MockProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]mockGrant // authorization codes, used once
+	tokens map[string]mockGrant // access tokens for the userinfo endpoint
+}
+
+type mockGrant struct {
+	email       string
+	verified    bool
+	nonce       string
+	challenge   string
+	redirectURI string
+	expire      time.Time
+}
+
+func NewMockProvider(issuer, clientID, clientSecret string) (*MockProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &MockProvider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]mockGrant),
+		tokens:       make(map[string]mockGrant),
+	}, nil
+}
+
+func (m *MockProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, discovery{
+			Issuer:                m.issuer,
+			AuthorizationEndpoint: m.issuer + "/authorize",
+			TokenEndpoint:         m.issuer + "/token",
+			JWKSURI:               m.issuer + "/jwks",
+			UserinfoEndpoint:      m.issuer + "/userinfo",
+		})
+	case "/jwks":
+		pub := m.key.PublicKey
+		writeJSON(w, http.StatusOK, map[string][]jsonWebKey{"keys": {{
+			Kty: "RSA",
+			Kid: mockKeyID,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	case "/authorize":
+		m.authorize(w, r)
+	case "/token":
+		m.token(w, r)
+	case "/userinfo":
+		m.userinfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var mockLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><body>
+<h3>Mock OIDC login</h3>
+<form method="get">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<input name="email" placeholder="email" required>
+<label><input type="checkbox" name="email_verified" value="true" checked> verified</label>
+<button type="submit">Login</button>
+</form>
+</body></html>`))
+
+// authorize shows the login page, or redirects back to the client with a code if the email is given
+func (m *MockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != m.clientID || q.Get("response_type") != "code" {
+		http.Error(w, "unknown client or response type", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("email") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = mockLoginPage.Execute(w, q)
+		return
+	}
+	if q.Get("code_challenge") != "" && q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "only S256 code challenges are supported", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	m.mu.Lock()
+	m.codes[code] = mockGrant{
+		email:       q.Get("email"),
+		verified:    q.Get("email_verified") == "true",
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: redirectURI.String(),
+		expire:      time.Now().Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (m *MockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != m.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(m.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, tokenResponse{Error: "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	code := r.PostForm.Get("code")
+	grant, exists := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+	if r.PostForm.Get("grant_type") != "authorization_code" || !exists || time.Now().After(grant.expire) ||
+		grant.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_grant"})
+		return
+	}
+	if grant.challenge != "" && challenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_grant", ErrorDescription: "wrong code verifier"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                m.issuer,
+		"sub":                grant.email,
+		"aud":                m.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"email":              grant.email,
+		"email_verified":     grant.verified,
+		"preferred_username": strings.Split(grant.email, "@")[0],
+	}
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = mockKeyID
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, tokenResponse{Error: "server_error"})
+		return
+	}
+
+	accessToken := randomString()
+	grant.expire = now.Add(time.Hour)
+	m.mu.Lock()
+	m.tokens[accessToken] = grant
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func (m *MockProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	m.mu.Lock()
+	grant, exists := m.tokens[accessToken]
+	m.mu.Unlock()
+	if !exists || time.Now().After(grant.expire) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sub":                grant.email,
+		"email":              grant.email,
+		"email_verified":     grant.verified,
+		"preferred_username": strings.Split(grant.email, "@")[0],
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	s, err := genRandom()
+	if err != nil {
+		panic(fmt.Sprintf("couldn't read random bytes: %v", err))
+	}
+	return s
+}
@@ -0,0 +1,175 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/kvstorages"
+)
+
+// stateTTL is the time a user has to login in the page of the provider
+const stateTTL = 10 * time.Minute
+
+// Identity is the user that is logged in by a provider
+type Identity struct {
+	Subject       string // id of the user in the provider
+	Email         string
+	EmailVerified bool
+	Username      string // preferred username, may be empty
+}
+
+// Provider is an authorization server that logs in users with the authorization code flow
+type Provider interface {
+	// AuthURL gives the login page of the provider, which redirects back to the callback with a code and the state
+	AuthURL(ctx context.Context, state, nonce, challenge string) (string, error)
+	// Exchange gets the identity of the user from the code, the nonce and verifier are the ones of its AuthURL.
+	// a code or token that is rejected gives pkg.ErrForbidden
+	Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error)
+}
+
+// Handler logs in users by the configured providers. unknown providers give pkg.ErrNotFound and
+// unknown, expired or used states give pkg.ErrForbidden
+type Handler interface {
+	// Begin gives the url that the user should be redirected to for logging in by the provider
+	Begin(ctx context.Context, provider string) (string, error)
+	// Finish checks the state given to the callback and gets the identity of the user from the code
+	Finish(ctx context.Context, provider, code, state string) (Identity, error)
+}
+
+func NewHandler(c configs.SectionOIDC, storage kvstorages.KVStorage) (Handler, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]Provider)
+	for _, p := range c.ProviderConfigs {
+		if p.ClientID == "" {
+			return nil, fmt.Errorf("client id of oidc provider %v is empty", p.Name)
+		}
+		redirectURL := fmt.Sprintf("%s/%s/callback", strings.TrimSuffix(c.RedirectURL, "/"), p.Name)
+		switch p.Type {
+		case "", "oidc":
+			if p.Issuer == "" {
+				return nil, fmt.Errorf("issuer of oidc provider %v is empty", p.Name)
+			}
+			providers[p.Name] = newOIDCProvider(p, redirectURL, client)
+		case "github":
+			providers[p.Name] = newGithubProvider(p, redirectURL, client)
+		default:
+			return nil, fmt.Errorf("unknown type %v of oidc provider %v", p.Type, p.Name)
+		}
+	}
+
+	return &HandlerImp{
+		providers: providers,
+		storage:   storage,
+	}, nil
+}
+
+type HandlerImp struct {
+	providers map[string]Provider
+	storage   kvstorages.KVStorage
+}
+
+// loginState is kept for every login until its callback, the verifier is the pkce secret of the login
+type loginState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func (h *HandlerImp) Begin(ctx context.Context, name string) (string, error) {
+	provider, err := h.provider(name)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := genRandom()
+	if err != nil {
+		return "", err
+	}
+	ls := loginState{Provider: name}
+	if ls.Nonce, err = genRandom(); err != nil {
+		return "", err
+	}
+	if ls.Verifier, err = genRandom(); err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(ls)
+	if err != nil {
+		return "", err
+	}
+	if err := h.storage.SaveWithTTL(ctx, stateKey(state), string(value), stateTTL); err != nil {
+		return "", err
+	}
+
+	return provider.AuthURL(ctx, state, ls.Nonce, challenge(ls.Verifier))
+}
+
+func (h *HandlerImp) Finish(ctx context.Context, name, code, state string) (Identity, error) {
+	provider, err := h.provider(name)
+	if err != nil {
+		return Identity{}, err
+	}
+	if state == "" || code == "" {
+		return Identity{}, pkg.ErrForbidden
+	}
+
+	value, err := h.storage.Get(ctx, stateKey(state))
+	if errors.Is(err, pkg.ErrNotFound) {
+		return Identity{}, fmt.Errorf("unknown state: %w", pkg.ErrForbidden)
+	} else if err != nil {
+		return Identity{}, err
+	}
+	// the state is deleted before using, so a callback can't be replayed
+	deleted, err := h.storage.CompareAndDelete(ctx, stateKey(state), value)
+	if err != nil {
+		return Identity{}, err
+	}
+	if !deleted {
+		return Identity{}, fmt.Errorf("state is used: %w", pkg.ErrForbidden)
+	}
+
+	var ls loginState
+	if err := json.Unmarshal([]byte(value), &ls); err != nil {
+		return Identity{}, err
+	}
+	if ls.Provider != name {
+		return Identity{}, fmt.Errorf("state belongs to provider %v: %w", ls.Provider, pkg.ErrForbidden)
+	}
+
+	return provider.Exchange(ctx, code, ls.Nonce, ls.Verifier)
+}
+
+func (h *HandlerImp) provider(name string) (Provider, error) {
+	provider, exists := h.providers[name]
+	if !exists {
+		return nil, fmt.Errorf("oidc provider %v: %w", name, pkg.ErrNotFound)
+	}
+	return provider, nil
+}
+
+func stateKey(state string) string {
+	return fmt.Sprintf("oidc_state/%s", state)
+}
+
+func genRandom() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge is the S256 pkce challenge of the verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
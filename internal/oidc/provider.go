@@ -0,0 +1,373 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+)
+
+// discovery is the part of the openid configuration of an issuer that is used
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // some providers give it as a string
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// oidcProvider is a generic openid connect provider, its endpoints and keys are read from the discovery
+// document of the issuer on the first use, so the server can start while the issuer is not reachable
+type oidcProvider struct {
+	conf        configs.SectionOIDCProvider
+	redirectURL string
+	client      *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]any // public keys of the issuer by their kid
+}
+
+func newOIDCProvider(conf configs.SectionOIDCProvider, redirectURL string, client *http.Client) *oidcProvider {
+	if conf.Scopes == "" {
+		conf.Scopes = "openid email profile"
+	}
+	return &oidcProvider{
+		conf:        conf,
+		redirectURL: redirectURL,
+		client:      client,
+	}
+}
+
+func (p *oidcProvider) AuthURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	return authURL(d.AuthorizationEndpoint, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.conf.ClientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {p.conf.Scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	})
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	token, err := exchangeCode(ctx, p.client, d.TokenEndpoint, p.conf, p.redirectURL, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+	if token.IDToken == "" {
+		return Identity{}, fmt.Errorf("provider %v gave no id token: %w", p.conf.Name, pkg.ErrForbidden)
+	}
+
+	claims, err := p.verifyIDToken(ctx, d, token.IDToken, nonce)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		Username:      claims.PreferredUsername,
+	}
+	if identity.Email == "" && d.UserinfoEndpoint != "" {
+		return p.userinfo(ctx, d, token.AccessToken, identity)
+	}
+	return identity, nil
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, d *discovery, idToken, nonce string) (idTokenClaims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, d, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.conf.ClientID),
+	)
+	if err != nil {
+		return idTokenClaims{}, fmt.Errorf("invalid id token: %v: %w", err, pkg.ErrForbidden)
+	}
+
+	if claims.ExpiresAt == nil || claims.Subject == "" {
+		return idTokenClaims{}, fmt.Errorf("id token has no exp or sub: %w", pkg.ErrForbidden)
+	}
+	if claims.Nonce != nonce {
+		return idTokenClaims{}, fmt.Errorf("nonce of id token doesn't match: %w", pkg.ErrForbidden)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.conf.ClientID {
+		return idTokenClaims{}, fmt.Errorf("id token is issued for %v: %w", claims.AuthorizedParty, pkg.ErrForbidden)
+	}
+	return claims, nil
+}
+
+// userinfo fills the email of the identity from the userinfo endpoint, for providers that don't put it in id tokens
+func (p *oidcProvider) userinfo(ctx context.Context, d *discovery, accessToken string, identity Identity) (Identity, error) {
+	var info struct {
+		Subject           string `json:"sub"`
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := getJSON(ctx, p.client, d.UserinfoEndpoint, accessToken, &info); err != nil {
+		return Identity{}, err
+	}
+	if info.Subject != identity.Subject {
+		return Identity{}, fmt.Errorf("userinfo is for another subject: %w", pkg.ErrForbidden)
+	}
+
+	identity.Email = info.Email
+	identity.EmailVerified = isTrue(info.EmailVerified)
+	if identity.Username == "" {
+		identity.Username = info.PreferredUsername
+	}
+	return identity, nil
+}
+
+func (p *oidcProvider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.conf.Issuer, "/")
+	var d discovery
+	if err := getJSON(ctx, p.client, issuer+"/.well-known/openid-configuration", "", &d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != issuer && d.Issuer != p.conf.Issuer {
+		return nil, fmt.Errorf("discovery of %v is for issuer %v", p.conf.Issuer, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery of %v has missing endpoints", p.conf.Issuer)
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// getKey gives the key of the kid, the keys are fetched again for an unknown kid since issuers rotate them
+func (p *oidcProvider) getKey(ctx context.Context, d *discovery, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, exists := p.keys[kid]; exists {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, d.JWKSURI, "", &set); err != nil {
+		return nil, err
+	}
+	p.keys = make(map[string]any)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			pkg.Log.WithField("module", "oidc").Warnf("skipping key %v of %v: %v", k.Kid, d.Issuer, err)
+			continue
+		}
+		p.keys[k.Kid] = key
+	}
+
+	if key, exists := p.keys[kid]; exists {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %v", kid)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unknown curve %v", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid ec point size")
+		}
+		// ecdh checks that the point is on the curve
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unknown key type %v", k.Kty)
+	}
+}
+
+func authURL(endpoint string, params url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// exchangeCode gets the tokens of the code from the token endpoint of the provider
+func exchangeCode(ctx context.Context, client *http.Client, endpoint string, conf configs.SectionOIDCProvider,
+	redirectURL, code, verifier string) (tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {conf.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if conf.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	defer resp.Body.Close()
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return tokenResponse{}, fmt.Errorf("error on decoding token response of %v, status %v: %v", conf.Name, resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return tokenResponse{}, fmt.Errorf("provider %v rejected the code: %v %v: %w", conf.Name, token.Error, token.ErrorDescription, pkg.ErrForbidden)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tokenResponse{}, fmt.Errorf("token endpoint of %v gave status %v", conf.Name, resp.StatusCode)
+	}
+	if token.AccessToken == "" {
+		return tokenResponse{}, fmt.Errorf("provider %v gave no access token: %w", conf.Name, pkg.ErrForbidden)
+	}
+	return token, nil
+}
+
+// getJSON decodes the response of a get request, with the bearer token if it's not empty
+func getJSON(ctx context.Context, client *http.Client, endpoint, bearer string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%v gave status %v: %w", endpoint, resp.StatusCode, pkg.ErrForbidden)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v gave status %v", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func isTrue(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
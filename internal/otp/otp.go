@@ -2,8 +2,11 @@ package otp
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"time"
 
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/kvstorages"
@@ -11,24 +14,38 @@ import (
 
 type OTPHandler interface {
 	Gen(ctx context.Context, userID, typ string) (string, error)
+	// Check gives pkg.ErrForbidden for a wrong code, and pkg.ErrNotFound if there is no code or it is expired,
+	// used, or invalidated after too many wrong attempts. a right code can be used only once
 	Check(ctx context.Context, userID, typ, val string) error
 }
 
-func NewOTPHandler(storage kvstorages.KVStorage) OTPHandler {
+// NewOTPHandler makes an OTPHandler whose codes expire after ttl and are invalidated after maxAttempts
+// wrong attempts, zero means no limit
+func NewOTPHandler(storage kvstorages.KVStorage, maxAttempts int, ttl time.Duration) OTPHandler {
 	return &OTPHandlerImp{
-		storage: storage,
+		storage:     storage,
+		maxAttempts: maxAttempts,
+		ttl:         ttl,
 	}
 }
 
-// TODO: use redis or memcache instead of map
 type OTPHandlerImp struct {
-	storage kvstorages.KVStorage
+	storage     kvstorages.KVStorage
+	maxAttempts int
+	ttl         time.Duration
 }
 
 func (o *OTPHandlerImp) Gen(ctx context.Context, userID, typ string) (string, error) {
 	k := fmt.Sprintf("%s/%s", typ, userID)
-	v := fmt.Sprintf("%06d", rand.Intn(1000000))
-	return v, o.storage.Save(ctx, k, v)
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	v := fmt.Sprintf("%06d", n.Int64())
+	if err := o.storage.Delete(ctx, attemptsKey(k)); err != nil {
+		return "", err
+	}
+	return v, o.storage.SaveWithTTL(ctx, k, v, o.ttl)
 }
 
 func (o *OTPHandlerImp) Check(ctx context.Context, userID, typ, val string) error {
@@ -37,8 +54,44 @@ func (o *OTPHandlerImp) Check(ctx context.Context, userID, typ, val string) erro
 	if err != nil {
 		return err
 	}
-	if ans != val {
+	if subtle.ConstantTimeCompare([]byte(ans), []byte(val)) != 1 {
+		return o.fail(ctx, k)
+	}
+
+	// the code may be used by another request at the same time, only one of them deletes it
+	deleted, err := o.storage.CompareAndDelete(ctx, k, ans)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return pkg.ErrNotFound
+	}
+	return o.storage.Delete(ctx, attemptsKey(k))
+}
+
+// fail counts a wrong attempt of the code and invalidates it if it has too many
+func (o *OTPHandlerImp) fail(ctx context.Context, k string) error {
+	if o.maxAttempts == 0 {
 		return pkg.ErrForbidden
 	}
-	return nil
+
+	attempts, err := o.storage.Incr(ctx, attemptsKey(k))
+	if err != nil {
+		return err
+	}
+	if attempts == 1 && o.ttl > 0 {
+		if err := o.storage.Expire(ctx, attemptsKey(k), o.ttl); err != nil {
+			return err
+		}
+	}
+	if attempts >= int64(o.maxAttempts) {
+		if err := o.storage.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return pkg.ErrForbidden
+}
+
+func attemptsKey(k string) string {
+	return fmt.Sprintf("attempts/%s", k)
 }
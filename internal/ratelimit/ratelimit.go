@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/kvstorages"
+)
+
+// Limiter counts the attempts of keys in a window of time, the window is restarted when a key reaches the
+// limit so the key is blocked for a whole window. a limit of zero means there is no limit
+type Limiter interface {
+	// Hit counts an attempt of the key and gives the time to wait if it is over the limit
+	Hit(ctx context.Context, key string) (time.Duration, error)
+	// Blocked gives the time to wait if the key has reached the limit, without counting an attempt
+	Blocked(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+func NewLimiter(storage kvstorages.KVStorage, prefix string, limit int, window time.Duration) Limiter {
+	return &LimiterImp{
+		storage: storage,
+		prefix:  prefix,
+		limit:   limit,
+		window:  window,
+	}
+}
+
+type LimiterImp struct {
+	storage kvstorages.KVStorage
+	prefix  string
+	limit   int
+	window  time.Duration
+}
+
+func (l *LimiterImp) Hit(ctx context.Context, key string) (time.Duration, error) {
+	if l.limit == 0 {
+		return 0, nil
+	}
+	count, err := l.storage.Incr(ctx, l.key(key))
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 || count == int64(l.limit) {
+		if err := l.storage.Expire(ctx, l.key(key), l.window); err != nil {
+			return 0, err
+		}
+	}
+
+	if count > int64(l.limit) {
+		return l.wait(ctx, key)
+	}
+	return 0, nil
+}
+
+func (l *LimiterImp) Blocked(ctx context.Context, key string) (time.Duration, error) {
+	if l.limit == 0 {
+		return 0, nil
+	}
+	val, err := l.storage.Get(ctx, l.key(key))
+	if errors.Is(err, pkg.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if count >= l.limit {
+		return l.wait(ctx, key)
+	}
+	return 0, nil
+}
+
+func (l *LimiterImp) Reset(ctx context.Context, key string) error {
+	if l.limit == 0 {
+		return nil
+	}
+	return l.storage.Delete(ctx, l.key(key))
+}
+
+// wait gives the time until the window of the key ends
+func (l *LimiterImp) wait(ctx context.Context, key string) (time.Duration, error) {
+	wait, err := l.storage.TTL(ctx, l.key(key))
+	if errors.Is(err, pkg.ErrNotFound) {
+		return 0, nil
+	}
+	return wait, err
+}
+
+func (l *LimiterImp) key(key string) string {
+	return fmt.Sprintf("%s/%s", l.prefix, key)
+}
+
+// Seconds rounds the wait up to seconds, as it is sent in the Retry-After header
+func Seconds(wait time.Duration) int64 {
+	return int64(math.Ceil(wait.Seconds()))
+}
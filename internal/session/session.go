@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/kvstorages"
+	"github.com/ocontest/backend/pkg/structs"
+)
+
+// revoked is stored instead of the refresh token id of a session that is logged out
+const revoked = "revoked"
+
+// errReused is given by Check for a refresh token that is replaced by Rotate
+var errReused = fmt.Errorf("refresh token is reused: %w", pkg.ErrForbidden)
+
+// SessionHandler keeps the sessions of users in the kv storage. a session is made on every login and keeps
+// the id of its last refresh token, so a refresh token can be used only once.
+// every check gives pkg.ErrForbidden if the session is revoked or the token is not valid anymore
+type SessionHandler interface {
+	// Start makes a new session for the user, the claims have the ids of the session and its first refresh token
+	Start(ctx context.Context, userID int64) (structs.TokenClaims, error)
+	// Rotate replaces the refresh token of the claims with a new one. using a replaced refresh token again
+	// revokes the session, since it means the token is stolen
+	Rotate(ctx context.Context, claims structs.TokenClaims) (structs.TokenClaims, error)
+	Check(ctx context.Context, claims structs.TokenClaims) error
+	Revoke(ctx context.Context, sessionID string) error
+	// RevokeAll revokes every session of the user
+	RevokeAll(ctx context.Context, userID int64) error
+}
+
+// NewSessionHandler makes a SessionHandler whose sessions are forgotten after ttl without renewing,
+// it should be the duration of refresh tokens
+func NewSessionHandler(storage kvstorages.KVStorage, ttl time.Duration) SessionHandler {
+	return &SessionHandlerImp{
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+type SessionHandlerImp struct {
+	storage kvstorages.KVStorage
+	ttl     time.Duration
+}
+
+func (s *SessionHandlerImp) Start(ctx context.Context, userID int64) (structs.TokenClaims, error) {
+	generation, err := s.generation(ctx, userID)
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	sessionID, err := genID()
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	tokenID, err := genID()
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+
+	claims := structs.TokenClaims{
+		UserID:     userID,
+		SessionID:  sessionID,
+		TokenID:    tokenID,
+		Generation: generation,
+	}
+	return claims, s.storage.SaveWithTTL(ctx, sessionKey(sessionID), tokenID, s.ttl)
+}
+
+func (s *SessionHandlerImp) Rotate(ctx context.Context, claims structs.TokenClaims) (structs.TokenClaims, error) {
+	err := s.Check(ctx, claims)
+	if errors.Is(err, errReused) {
+		if err := s.Revoke(ctx, claims.SessionID); err != nil {
+			return structs.TokenClaims{}, err
+		}
+		return structs.TokenClaims{}, pkg.ErrForbidden
+	}
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	// another renew with the same token may have replaced it since the check
+	replaced, err := s.storage.CompareAndDelete(ctx, sessionKey(claims.SessionID), claims.TokenID)
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	if !replaced {
+		if err := s.Revoke(ctx, claims.SessionID); err != nil {
+			return structs.TokenClaims{}, err
+		}
+		return structs.TokenClaims{}, pkg.ErrForbidden
+	}
+
+	claims.TokenID, err = genID()
+	if err != nil {
+		return structs.TokenClaims{}, err
+	}
+	return claims, s.storage.SaveWithTTL(ctx, sessionKey(claims.SessionID), claims.TokenID, s.ttl)
+}
+
+// Check checks that the session of the claims is not revoked, refresh tokens should also be the last
+// refresh token of their session
+func (s *SessionHandlerImp) Check(ctx context.Context, claims structs.TokenClaims) error {
+	if claims.SessionID == "" {
+		return pkg.ErrForbidden
+	}
+	tokenID, err := s.storage.Get(ctx, sessionKey(claims.SessionID))
+	if errors.Is(err, pkg.ErrNotFound) || tokenID == revoked {
+		return pkg.ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+
+	generation, err := s.generation(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.Generation != generation {
+		return pkg.ErrForbidden
+	}
+
+	if claims.Type == "refresh" && claims.TokenID != tokenID {
+		return errReused
+	}
+	return nil
+}
+
+// Revoke marks the session as revoked, the mark is kept until the tokens of the session are expired
+func (s *SessionHandlerImp) Revoke(ctx context.Context, sessionID string) error {
+	return s.storage.SaveWithTTL(ctx, sessionKey(sessionID), revoked, s.ttl)
+}
+
+func (s *SessionHandlerImp) RevokeAll(ctx context.Context, userID int64) error {
+	_, err := s.storage.Incr(ctx, generationKey(userID))
+	return err
+}
+
+// generation gives the number of times the user is logged out of all sessions
+func (s *SessionHandlerImp) generation(ctx context.Context, userID int64) (int64, error) {
+	val, err := s.storage.Get(ctx, generationKey(userID))
+	if errors.Is(err, pkg.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session/%s", sessionID)
+}
+
+func generationKey(userID int64) string {
+	return fmt.Sprintf("session_generation/%d", userID)
+}
+
+func genID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
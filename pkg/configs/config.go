@@ -1,6 +1,7 @@
 package configs
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"time"
@@ -24,6 +25,7 @@ type OContestConf struct {
 	Auth    SectionAuth    `yaml:"auth"`
 	MinIO   SectionMinIO   `yaml:"minio"`
 	Judge   SectionJudge   `yaml:"judge"`
+	OIDC    SectionOIDC    `yaml:"oidc"`
 }
 
 type SectionLog struct {
@@ -62,10 +64,14 @@ type SectionMongo struct {
 }
 
 type SectionNats struct {
-	Url          string        `yaml:"url"`
-	Subject      string        `yaml:"subject"`
-	ReplyTimeout time.Duration `yaml:"reply_timeout"`
-	Queue        string        `yaml:"queue"`
+	Url             string        `yaml:"url"`
+	Subject         string        `yaml:"subject"`          // judge requests are published here
+	ResultSubject   string        `yaml:"result_subject"`   // judge results are published here
+	ProgressSubject string        `yaml:"progress_subject"` // runners report progress of judging here
+	Stream          string        `yaml:"stream"`           // jetstream stream name, results are kept in <stream>_RESULTS
+	Queue           string        `yaml:"queue"`            // durable consumer name shared by runners
+	AckWait         time.Duration `yaml:"ack_wait"`         // a message is redelivered if it is not acked in this time
+	MaxDeliver      int           `yaml:"max_deliver"`
 }
 
 type SectionJWT struct {
@@ -73,13 +79,34 @@ type SectionJWT struct {
 }
 
 type SectionSMTP struct {
-	From     string `yaml:"from"`
-	Password string `yaml:"password"`
-	Enabled  bool   `yaml:"enabled"`
+	From       string        `yaml:"from"`
+	Password   string        `yaml:"password"`
+	Enabled    bool          `yaml:"enabled"`
+	Host       string        `yaml:"host"`        // defaults to smtp.gmail.com
+	Port       int           `yaml:"port"`        // defaults to 587
+	Username   string        `yaml:"username"`    // defaults to from
+	TLS        string        `yaml:"tls"`         // starttls (default), tls for implicit tls, or none
+	Auth       string        `yaml:"auth"`        // plain (default), login, cram_md5 or none
+	Timeout    time.Duration `yaml:"timeout"`     // of connecting and sending an email, defaults to 10s
+	Templates  string        `yaml:"templates"`   // directory of templates that override the default ones
+	QueueSize  int           `yaml:"queue_size"`  // emails waiting to be sent, defaults to 100
+	Retries    int           `yaml:"retries"`     // defaults to 3
+	RetryDelay time.Duration `yaml:"retry_delay"` // it is doubled after every retry, defaults to 5s
 }
 
 type SectionAuth struct {
-	Duration SectionAuthDuration `yaml:"duration"`
+	Duration     SectionAuthDuration  `yaml:"duration"`
+	PasswordCost int                  `yaml:"password_cost"` // bcrypt cost of password hashes, defaults to 10
+	RateLimit    SectionAuthRateLimit `yaml:"rate_limit"`
+}
+
+// SectionAuthRateLimit limits the attempts on auth endpoints, zero values mean no limit
+type SectionAuthRateLimit struct {
+	Requests    int           `yaml:"requests"` // requests of an ip to the auth endpoints in a window
+	Window      time.Duration `yaml:"window"`
+	MaxFailures int           `yaml:"max_failures"` // failed logins of an account before it is locked
+	Lockout     time.Duration `yaml:"lockout"`
+	OTPAttempts int           `yaml:"otp_attempts"` // wrong codes before an otp is invalidated
 }
 
 type SectionAuthDuration struct {
@@ -88,10 +115,29 @@ type SectionAuthDuration struct {
 	VerifyEmail  time.Duration `yaml:"verify_email"`
 }
 
+type SectionOIDC struct {
+	Providers   string `yaml:"providers"`    // comma separated names of the login providers, like google,github
+	RedirectURL string `yaml:"redirect_url"` // the callback of a provider is <redirect_url>/<name>/callback
+	// ProviderConfigs are read from oidc.<name>.* for every name in Providers, like OCONTEST_OIDC_GOOGLE_CLIENT_ID
+	ProviderConfigs []SectionOIDCProvider `yaml:"-"`
+}
+
+type SectionOIDCProvider struct {
+	Name         string
+	Type         string // oidc (default) or github
+	Issuer       string // the discovery document is read from <issuer>/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	Scopes       string // space separated, defaults to "openid email profile" for oidc and "read:user user:email" for github
+}
+
 type SectionServer struct {
 	Host                   string        `yaml:"host"`
 	Port                   string        `yaml:"port"`
 	GracefulShutdownPeriod time.Duration `yaml:"graceful_shutdown_period"`
+	// TrustedProxies are comma separated ips or cidrs of the proxies whose X-Forwarded-For is used as the
+	// ip of clients, like the ip limiter. no proxy is trusted if it's empty
+	TrustedProxies string `yaml:"trusted_proxies"`
 }
 
 type SectionMinIO struct {
@@ -105,8 +151,14 @@ type SectionMinIO struct {
 }
 
 type SectionJudge struct {
-	EnableRunner bool        `yaml:"enable_runner"` // if it is set, then there is no need for separate runner app. not recommended
-	Nats         SectionNats `yaml:"nats"`
+	EnableRunner bool          `yaml:"enable_runner"` // if it is set, then there is no need for separate runner app. not recommended
+	Nats         SectionNats   `yaml:"nats"`
+	Runner       SectionRunner `yaml:"runner"`
+}
+
+type SectionRunner struct {
+	Type       string `yaml:"type"`        // either 'dummy' or 'isolated', isolated uses linux namespaces and seccomp
+	CgroupRoot string `yaml:"cgroup_root"` // a delegated cgroup v2 directory, memory limits fall back to rlimits if it is empty
 }
 
 func getElements(path string, ref reflect.Type) []string {
@@ -119,6 +171,9 @@ func getElements(path string, ref reflect.Type) []string {
 	var ans []string
 	for i := 0; i < ref.NumField(); i++ {
 		field := ref.Field(i)
+		if field.Tag.Get("yaml") == "-" {
+			continue
+		}
 		fieldPath := strings.ToLower(basePath + field.Tag.Get("yaml"))
 		if field.Type.Kind() == reflect.Struct {
 			ans = append(ans, getElements(fieldPath, field.Type)...)
@@ -142,12 +197,26 @@ func BindEnvVariables() {
 
 func AddVariablesWithUnderscore(c *OContestConf) {
 	c.Judge.EnableRunner = viper.GetBool("judge.enable_runner")
-	c.Judge.Nats.ReplyTimeout = viper.GetDuration("judge.nats.reply_timeout")
+	c.Judge.Nats.ResultSubject = viper.GetString("judge.nats.result_subject")
+	c.Judge.Nats.ProgressSubject = viper.GetString("judge.nats.progress_subject")
+	c.Judge.Nats.AckWait = viper.GetDuration("judge.nats.ack_wait")
+	c.Judge.Nats.MaxDeliver = viper.GetInt("judge.nats.max_deliver")
+	c.Judge.Runner.CgroupRoot = viper.GetString("judge.runner.cgroup_root")
 	c.MinIO.AccessKey = viper.GetString("minio.access_key")
 	c.MinIO.SecretKey = viper.GetString("minio.secret_key")
+	c.Server.TrustedProxies = viper.GetString("server.trusted_proxies")
+	c.OIDC.RedirectURL = viper.GetString("oidc.redirect_url")
+	c.SMTP.QueueSize = viper.GetInt("smtp.queue_size")
+	c.SMTP.RetryDelay = viper.GetDuration("smtp.retry_delay")
 	c.Auth.Duration.AccessToken = viper.GetDuration("auth.duration.access_token")
 	c.Auth.Duration.RefreshToken = viper.GetDuration("auth.duration.refresh_token")
 	c.Auth.Duration.VerifyEmail = viper.GetDuration("auth.duration.verify_email")
+	c.Auth.PasswordCost = viper.GetInt("auth.password_cost")
+	c.Auth.RateLimit.Requests = viper.GetInt("auth.rate_limit.requests")
+	c.Auth.RateLimit.Window = viper.GetDuration("auth.rate_limit.window")
+	c.Auth.RateLimit.MaxFailures = viper.GetInt("auth.rate_limit.max_failures")
+	c.Auth.RateLimit.Lockout = viper.GetDuration("auth.rate_limit.lockout")
+	c.Auth.RateLimit.OTPAttempts = viper.GetInt("auth.rate_limit.otp_attempts")
 	c.Server.GracefulShutdownPeriod = viper.GetDuration("server.graceful_shutdown_period")
 	c.SQLDB.DBType = viper.GetString("sql_db.type")
 	c.SQLDB.ConnUrl = viper.GetString("sql_db.conn_url")
@@ -158,6 +227,27 @@ func AddVariablesWithUnderscore(c *OContestConf) {
 	c.SQLDB.Postgres.Database = viper.GetString("sql_db.postgres.database")
 }
 
+// getOIDCProviders reads the config of every provider in names, their keys are not known before reading names
+func getOIDCProviders(names string) []SectionOIDCProvider {
+	var ans []SectionOIDCProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key := func(k string) string { return fmt.Sprintf("oidc.%s.%s", name, k) }
+		ans = append(ans, SectionOIDCProvider{
+			Name:         name,
+			Type:         viper.GetString(key("type")),
+			Issuer:       viper.GetString(key("issuer")),
+			ClientID:     viper.GetString(key("client_id")),
+			ClientSecret: viper.GetString(key("client_secret")),
+			Scopes:       viper.GetString(key("scopes")),
+		})
+	}
+	return ans
+}
+
 // Loads the config
 func getConfig() *OContestConf {
 	viper.AutomaticEnv()           // reads from env
@@ -171,6 +261,7 @@ func getConfig() *OContestConf {
 	if err != nil {
 		panic("Error on unmarshal " + err.Error())
 	}
+	conf.OIDC.ProviderConfigs = getOIDCProviders(conf.OIDC.Providers)
 
 	return conf
 }
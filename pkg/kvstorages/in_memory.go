@@ -2,32 +2,162 @@ package kvstorages
 
 import (
 	"context"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/ocontest/backend/pkg"
 )
 
+// evictionPeriod is the period of removing the expired keys from memory, they are not seen by Get before that
+const evictionPeriod = time.Minute
+
+type inMemoryValue struct {
+	value     string
+	expiresAt time.Time // zero means it doesn't expire
+}
+
+func (v inMemoryValue) expired(now time.Time) bool {
+	return !v.expiresAt.IsZero() && !now.Before(v.expiresAt)
+}
+
 type InMemoryStorage struct {
-	mainStorage map[string]string
+	mu          sync.Mutex
+	mainStorage map[string]inMemoryValue
+	done        chan struct{}
 }
 
 func newInMemoryStorage() KVStorage {
-	return InMemoryStorage{
-		mainStorage: make(map[string]string),
+	ans := &InMemoryStorage{
+		mainStorage: make(map[string]inMemoryValue),
+		done:        make(chan struct{}),
 	}
+	go ans.evict()
+	return ans
 }
-func (i InMemoryStorage) Save(ctx context.Context, key, value string) error {
-	i.mainStorage[key] = value
+
+func (i *InMemoryStorage) Save(ctx context.Context, key, value string) error {
+	return i.SaveWithTTL(ctx, key, value, 0)
+}
+
+func (i *InMemoryStorage) SaveWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v := inMemoryValue{value: value}
+	if ttl > 0 {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+	i.mainStorage[key] = v
 	return nil
 }
 
-func (i InMemoryStorage) Get(ctx context.Context, key string) (string, error) {
-	val, exists := i.mainStorage[key]
+func (i *InMemoryStorage) Get(ctx context.Context, key string) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v, exists := i.get(key)
 	if !exists {
 		return "", pkg.ErrNotFound
 	}
-	return val, nil
+	return v.value, nil
 }
 
-func (i InMemoryStorage) Close() error{
+func (i *InMemoryStorage) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v, exists := i.get(key)
+	if !exists {
+		return pkg.ErrNotFound
+	}
+	v.expiresAt = time.Now().Add(ttl)
+	i.mainStorage[key] = v
 	return nil
 }
+
+func (i *InMemoryStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v, exists := i.get(key)
+	if !exists {
+		return 0, pkg.ErrNotFound
+	}
+	if v.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(v.expiresAt), nil
+}
+
+func (i *InMemoryStorage) Delete(ctx context.Context, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.mainStorage, key)
+	return nil
+}
+
+func (i *InMemoryStorage) Incr(ctx context.Context, key string) (int64, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v, exists := i.get(key)
+	var n int64
+	if exists {
+		var err error
+		if n, err = strconv.ParseInt(v.value, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	n++
+	v.value = strconv.FormatInt(n, 10)
+	i.mainStorage[key] = v
+	return n, nil
+}
+
+func (i *InMemoryStorage) CompareAndDelete(ctx context.Context, key, value string) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	v, exists := i.get(key)
+	if !exists || v.value != value {
+		return false, nil
+	}
+	delete(i.mainStorage, key)
+	return true, nil
+}
+
+func (i *InMemoryStorage) Close() error {
+	close(i.done)
+	return nil
+}
+
+// get gives the value of the key if it is not expired, the lock should be held
+func (i *InMemoryStorage) get(key string) (inMemoryValue, bool) {
+	v, exists := i.mainStorage[key]
+	if !exists || v.expired(time.Now()) {
+		return inMemoryValue{}, false
+	}
+	return v, true
+}
+
+// evict removes the expired keys every evictionPeriod until the storage is closed
+func (i *InMemoryStorage) evict() {
+	ticker := time.NewTicker(evictionPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.done:
+			return
+		case now := <-ticker.C:
+			i.mu.Lock()
+			for key, v := range i.mainStorage {
+				if v.expired(now) {
+					delete(i.mainStorage, key)
+				}
+			}
+			i.mu.Unlock()
+		}
+	}
+}
@@ -3,13 +3,26 @@ package kvstorages
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/ocontest/backend/pkg/configs"
 )
 
+// KVStorage keeps string values by key. Get gives pkg.ErrNotFound for missing and expired keys
 type KVStorage interface {
 	Save(ctx context.Context, key, value string) error
+	// SaveWithTTL saves the value and removes it after ttl
+	SaveWithTTL(ctx context.Context, key, value string, ttl time.Duration) error
 	Get(ctx context.Context, key string) (string, error)
+	// Expire removes an existing key after ttl
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// TTL gives the time until the key is removed, zero if it is kept forever. missing keys give pkg.ErrNotFound
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, key string) error
+	// Incr adds one to the integer in the key atomically and gives the result, a missing key is zero
+	Incr(ctx context.Context, key string) (int64, error)
+	// CompareAndDelete deletes the key atomically if its value is value, and tells whether it is deleted
+	CompareAndDelete(ctx context.Context, key, value string) (bool, error)
 	Close() error
 }
 
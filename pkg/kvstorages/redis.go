@@ -2,13 +2,23 @@ package kvstorages
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/configs"
 	"github.com/redis/go-redis/v9"
 )
 
+// compareAndDelete deletes KEYS[1] if its value is ARGV[1], it gives the number of deleted keys
+var compareAndDelete = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 type RedisStorage struct {
 	conn *redis.Client
 }
@@ -36,6 +46,10 @@ func (r RedisStorage) Save(ctx context.Context, key, value string) error {
 	return r.conn.Set(ctx, key, value, 0).Err()
 }
 
+func (r RedisStorage) SaveWithTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.conn.Set(ctx, key, value, ttl).Err()
+}
+
 func (r RedisStorage) Get(ctx context.Context, key string) (string, error) {
 	val, err := r.conn.Get(ctx, key).Result()
 	if err != nil {
@@ -48,6 +62,48 @@ func (r RedisStorage) Get(ctx context.Context, key string) (string, error) {
 	return val, nil
 }
 
+func (r RedisStorage) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	ok, err := r.conn.Expire(ctx, key, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+func (r RedisStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.conn.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// redis gives -2 for missing keys and -1 for the keys without expiry
+	switch ttl {
+	case -2:
+		return 0, pkg.ErrNotFound
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r RedisStorage) Delete(ctx context.Context, key string) error {
+	return r.conn.Del(ctx, key).Err()
+}
+
+func (r RedisStorage) Incr(ctx context.Context, key string) (int64, error) {
+	return r.conn.Incr(ctx, key).Result()
+}
+
+func (r RedisStorage) CompareAndDelete(ctx context.Context, key, value string) (bool, error) {
+	deleted, err := compareAndDelete.Run(ctx, r.conn, []string{key}, value).Int()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return deleted == 1, err
+}
+
 func (r RedisStorage) Close() error {
 	return r.conn.Close()
 }
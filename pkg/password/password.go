@@ -0,0 +1,46 @@
+package password
+
+import (
+	"errors"
+
+	"github.com/ocontest/backend/pkg"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHandler hashes the passwords of users, every hash has its own salt
+type PasswordHandler interface {
+	Hash(password string) (string, error)
+	// Compare gives pkg.ErrForbidden if the password doesn't match the hash
+	Compare(hash, password string) error
+	// IsHashed tells whether the stored password is made by Hash, old passwords are encrypted by aes
+	IsHashed(stored string) bool
+}
+
+type PasswordHandlerImp struct {
+	cost int
+}
+
+func NewPasswordHandler(cost int) PasswordHandler {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &PasswordHandlerImp{cost: cost}
+}
+
+func (p *PasswordHandlerImp) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
+	return string(hash), err
+}
+
+func (p *PasswordHandlerImp) Compare(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return pkg.ErrForbidden
+	}
+	return err
+}
+
+func (p *PasswordHandlerImp) IsHashed(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
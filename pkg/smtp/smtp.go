@@ -1,46 +1,134 @@
 package smtp
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"time"
+
 	"github.com/ocontest/backend/pkg"
 	"github.com/ocontest/backend/pkg/configs"
-	"net/smtp"
 )
 
-const smtpHost = "smtp.gmail.com"
-const smtpPort = "587"
+const (
+	defaultHost       = "smtp.gmail.com"
+	defaultPort       = 587
+	defaultTimeout    = 10 * time.Second
+	defaultQueueSize  = 100
+	defaultRetries    = 3
+	defaultRetryDelay = 5 * time.Second
+)
+
+var ErrQueueFull = errors.New("email queue is full")
 
 type Sender interface {
-	SendEmail(to, subject, body string) error
+	// SendEmail renders the html and text templates of the name with data and queues the email,
+	// the email is sent in background and retried if the mail server fails
+	SendEmail(to, subject, template string, data any) error
+	// Close waits for the queued emails to be sent until ctx is done
+	Close(ctx context.Context) error
+}
+
+type email struct {
+	to  string
+	msg []byte
 }
 
 type SenderImp struct {
-	from    string
-	auth    smtp.Auth
-	enabled bool
-}
-
-func NewSMTPHandler(c configs.SectionSMTP) Sender {
-	var ans SenderImp
-	if c.Enabled {
-		ans.from = c.From
-		ans.auth = smtp.PlainAuth("", c.From, c.Password, smtpHost)
-		ans.enabled = true
-	} else {
+	from       string
+	enabled    bool
+	transport  transport
+	templates  templates
+	queue      chan email
+	done       chan struct{}
+	retries    int
+	retryDelay time.Duration
+}
+
+func NewSMTPHandler(c configs.SectionSMTP) (Sender, error) {
+	templates, err := loadTemplates(c.Templates)
+	if err != nil {
+		return nil, err
+	}
+	ans := &SenderImp{
+		from:       c.From,
+		enabled:    c.Enabled,
+		templates:  templates,
+		queue:      make(chan email, orDefault(c.QueueSize, defaultQueueSize)),
+		done:       make(chan struct{}),
+		retries:    orDefault(c.Retries, defaultRetries),
+		retryDelay: orDefault(c.RetryDelay, defaultRetryDelay),
+	}
+	if !c.Enabled {
 		pkg.Log.Warning("smtp is not enabled, this should only be in dev environments, in production it must be enabled")
-		ans.enabled = false // not necessary, only for precaution
+		close(ans.done)
+		return ans, nil
+	}
+
+	ans.transport, err = newTransport(c)
+	if err != nil {
+		return nil, err
 	}
-	return ans
+	go ans.work()
+	return ans, nil
 }
-func (s SenderImp) SendEmail(to, subject, body string) error {
-	receivers := []string{to}
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";"
-	msg := []byte(fmt.Sprintf("to: %s\nsubject: %s\n%s\n\n%s", to, subject, mime, body))
+
+func (s *SenderImp) SendEmail(to, subject, template string, data any) error {
+	msg, err := s.templates.message(s.from, to, subject, template, data)
+	if err != nil {
+		return err
+	}
 	if !s.enabled {
 		pkg.Log.Info("SendEmail in smtp disable mode, logging message: \n", string(msg))
 		return nil
 	}
 
-	return smtp.SendMail(smtpHost+":"+smtpPort, s.auth, s.from, receivers, msg)
+	select {
+	case s.queue <- email{to: to, msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
 
+func (s *SenderImp) Close(ctx context.Context) error {
+	if s.enabled {
+		close(s.queue)
+	}
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work sends the queued emails until the queue is closed
+func (s *SenderImp) work() {
+	defer close(s.done)
+	logger := pkg.Log.WithField("module", "smtp")
+
+	for e := range s.queue {
+		delay := s.retryDelay
+		for attempt := 0; ; attempt++ {
+			err := s.transport.send(s.from, e.to, e.msg)
+			if err == nil {
+				break
+			}
+			if attempt == s.retries {
+				logger.Errorf("couldn't send email to %v after %v attempts: %v", e.to, attempt+1, err)
+				break
+			}
+			logger.Warningf("error on sending email to %v, retrying in %v: %v", e.to, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+func orDefault[T comparable](val, def T) T {
+	var zero T
+	if val == zero {
+		return def
+	}
+	return val
 }
@@ -0,0 +1,100 @@
+package smtp
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+)
+
+// defaultTemplates has an html and a text template for every email, <name>.html and <name>.txt
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+type templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// loadTemplates parses the default templates, the files in dir replace the default templates with the same name
+func loadTemplates(dir string) (templates, error) {
+	html, err := htmltemplate.ParseFS(defaultTemplates, "templates/*.html")
+	if err != nil {
+		return templates{}, err
+	}
+	text, err := texttemplate.ParseFS(defaultTemplates, "templates/*.txt")
+	if err != nil {
+		return templates{}, err
+	}
+	if dir == "" {
+		return templates{html: html, text: text}, nil
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.html")); len(files) != 0 {
+		if html, err = html.ParseFiles(files...); err != nil {
+			return templates{}, err
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.txt")); len(files) != 0 {
+		if text, err = text.ParseFiles(files...); err != nil {
+			return templates{}, err
+		}
+	}
+	return templates{html: html, text: text}, nil
+}
+
+// message renders the templates of the name and makes a multipart/alternative email of them
+func (t templates) message(from, to, subject, name string, data any) ([]byte, error) {
+	var textBody, htmlBody bytes.Buffer
+	if err := t.text.ExecuteTemplate(&textBody, name+".txt", data); err != nil {
+		return nil, fmt.Errorf("couldn't render text template %v: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&htmlBody, name+".html", data); err != nil {
+		return nil, fmt.Errorf("couldn't render html template %v: %w", name, err)
+	}
+
+	var msg bytes.Buffer
+	body := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", body.Boundary())
+
+	// the last part is the preferred one
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain", textBody.Bytes()},
+		{"text/html", htmlBody.Bytes()},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + `; charset="UTF-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
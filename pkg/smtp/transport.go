@@ -0,0 +1,131 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/ocontest/backend/pkg/configs"
+)
+
+// transport sends the emails to the mail server
+type transport struct {
+	host    string
+	addr    string
+	tls     string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+func newTransport(c configs.SectionSMTP) (transport, error) {
+	t := transport{
+		host:    orDefault(c.Host, defaultHost),
+		tls:     orDefault(c.TLS, "starttls"),
+		timeout: orDefault(c.Timeout, defaultTimeout),
+	}
+	t.addr = net.JoinHostPort(t.host, strconv.Itoa(orDefault(c.Port, defaultPort)))
+	if t.tls != "starttls" && t.tls != "tls" && t.tls != "none" {
+		return transport{}, fmt.Errorf("unknown smtp tls mode: %v", t.tls)
+	}
+
+	username := orDefault(c.Username, c.From)
+	switch orDefault(c.Auth, "plain") {
+	case "plain":
+		// net/smtp only sends plain auth over tls or to localhost
+		t.auth = smtp.PlainAuth("", username, c.Password, t.host)
+	case "login":
+		t.auth = loginAuth{username: username, password: c.Password}
+	case "cram_md5":
+		t.auth = smtp.CRAMMD5Auth(username, c.Password)
+	case "none":
+	default:
+		return transport{}, fmt.Errorf("unknown smtp auth type: %v", c.Auth)
+	}
+	return t, nil
+}
+
+func (t transport) send(from, to string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: t.timeout}
+	var conn net.Conn
+	var err error
+	if t.tls == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", t.addr, &tls.Config{ServerName: t.host})
+	} else {
+		conn, err = dialer.Dial("tcp", t.addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(t.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if t.tls == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: t.host}); err != nil {
+			return err
+		}
+	}
+	if t.auth != nil {
+		if err := client.Auth(t.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// loginAuth is the LOGIN auth mechanism, it is not in net/smtp but some servers only support it
+type loginAuth struct {
+	username, password string
+}
+
+func (a loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+}
@@ -24,6 +24,7 @@ type AuthenticateResponse struct {
 	Message      string `json:"message"`
 	AccessToken  string `json:"access_token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	RetryAfter   int64  `json:"retry_after,omitempty"` // seconds until a locked account can login again
 }
 
 type RequestLogin struct {
@@ -37,6 +38,16 @@ type RequestGetOTPLogin struct {
 	Email string `json:"email"`
 }
 
+type RequestForgotPassword struct {
+	Email string `json:"email"`
+}
+
+type RequestResetPassword struct {
+	Email    string `json:"email"`
+	OTP      string `json:"otp"`
+	Password string `json:"password"` // the new password
+}
+
 type RequestEditUser struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
@@ -47,6 +58,16 @@ type RequestEditUser struct {
 type ReponeGetUser struct {
 	Username string `json:"username"`
 	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+type RequestSetRole struct {
+	Role string `json:"role"` // one of Roles
+}
+
+type RequestAddContestStaff struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"` // one of ContestRoles
 }
 
 // PROBLEMS
@@ -54,8 +75,14 @@ type RequestCreateProblem struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	ContestID   int64  `json:"contest_id"`
-	IsPrivate   bool
-	Hardness    int64 `json:"hardness"`
+	IsPrivate   bool   `json:"is_private"`
+	Hardness    int64  `json:"hardness"`
+	TimeLimit   int64  `json:"time_limit"`   // milliseconds
+	MemoryLimit int64  `json:"memory_limit"` // megabytes
+}
+
+type RequestGrantProblemAccess struct {
+	UserID int64 `json:"user_id"`
 }
 
 type ResponseCreateProblem struct {
@@ -89,6 +116,10 @@ type ResponseGetProblem struct {
 	Hardness    int64  `json:"hardness"`
 	Description string `json:"description"`
 	IsOwned     bool   `json:"is_owned"`
+	IsPrivate   bool   `json:"is_private"`
+	TimeLimit   int64  `json:"time_limit"`
+	MemoryLimit int64  `json:"memory_limit"`
+	Checker     string `json:"checker"`
 }
 
 type RequestUpdateProblem struct {
@@ -96,6 +127,16 @@ type RequestUpdateProblem struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Hardness    int64  `json:"hardness"`
+	TimeLimit   int64  `json:"time_limit"`
+	MemoryLimit int64  `json:"memory_limit"`
+}
+
+type RequestSetChecker struct {
+	ProblemID int64
+	Type      string  `json:"type"`
+	Epsilon   float64 `json:"epsilon"`
+	Language  string  `json:"language"`
+	Code      string  `json:"code"`
 }
 
 // SUBMISSIONS
@@ -115,12 +156,23 @@ type ResponseGetSubmission struct {
 }
 
 type ResponseGetSubmissionResults struct {
+	Status         string    `json:"status"`
+	TestsDone      int       `json:"tests_done,omitempty"`
+	TestsTotal     int       `json:"tests_total,omitempty"`
 	Verdicts       []Verdict `json:"verdicts"`
 	ServiceMessage string    `json:"service_message"`
 	TestCaseID     int64     `json:"testcase_id"`
 	ErrorMessage   string    `json:"error_message"`
 }
 
+// RequestRejudge is for rejudging one submission, or all submissions of a problem or a contest. only one of the ids is set
+type RequestRejudge struct {
+	UserID       int64
+	ProblemID    int64
+	ContestID    int64
+	SubmissionID int64
+}
+
 type RequestListSubmissions struct {
 	ProblemID  int64 `json:"problem_id"`
 	UserID     int64 `json:"user_id"`
@@ -154,9 +206,11 @@ type ResponseListSubmissionsItem struct {
 
 // CONTESTS
 type RequestCreateContest struct {
-	Title     string `json:"title"`
-	StartTime int64  `json:"start_time"`
-	Duration  int    `json:"duration"`
+	Title      string `json:"title"`
+	StartTime  int64  `json:"start_time"`
+	Duration   int    `json:"duration"`
+	Type       string `json:"type"`
+	FreezeTime int64  `json:"freeze_time"`
 }
 
 type ResponseCreateContest struct {
@@ -169,24 +223,28 @@ type ResponseGetContest struct {
 	Problems       []ContestProblem   `json:"problems"`
 	StartTime      int64              `json:"start_time"`
 	Duration       int                `json:"duration"`
+	Type           string             `json:"type"`
+	FreezeTime     int64              `json:"freeze_time,omitempty"`
 	RegisterStatus RegistrationStatus `json:"register_status,omitempty"`
 }
 
 type RequestListContests struct {
-	UserID       int64 `json:"user_id"`
-	Descending   bool  `json:"descending"`
-	Limit        int   `json:"limit"`
-	Offset       int   `json:"offset"`
-	MyContest    bool  `json:"my_contest"`
-	Started      bool  `json:"started"`
-	OwnedContest bool  `json:"owned_contest"`
-	GetCount     bool  `json:"get_count"`
+	UserID       int64  `json:"user_id"`
+	Descending   bool   `json:"descending"`
+	Limit        int    `json:"limit"`
+	Offset       int    `json:"offset"`
+	MyContest    bool   `json:"my_contest"`
+	Status       string `json:"status"` // one of ContestStatuses
+	OwnedContest bool   `json:"owned_contest"`
+	GetCount     bool   `json:"get_count"`
 }
 
 type RequestUpdateContest struct {
-	Title     string `json:"title"`
-	StartTime int64  `json:"start_time"`
-	Duration  int    `json:"duration"`
+	Title      string `json:"title"`
+	StartTime  int64  `json:"start_time"`
+	Duration   int    `json:"duration"`
+	Type       string `json:"type"`
+	FreezeTime int64  `json:"freeze_time"`
 }
 
 type ResponseListContestsItem struct {
@@ -207,11 +265,22 @@ type RequestAddProblemContest struct {
 
 type RequestGetScoreboard struct {
 	ContestID int64
+	UserID    int64
 	GetCount  bool
+	Virtual   bool // rank the virtual participants with the users of the contest
 	Limit     int
 	Offset    int
 }
 
+// ResponseRevealSubmission is the submission whose result is revealed from a frozen scoreboard
+type ResponseRevealSubmission struct {
+	SubmissionID int64 `json:"submission_id"`
+	UserID       int64 `json:"user_id"`
+	ProblemID    int64 `json:"problem_id"`
+	Score        int   `json:"score"`
+	Remaining    int   `json:"remaining"` // hidden submissions after this one
+}
+
 type RequestRemoveProblemContest struct {
 	ContestID int64 `json:"contest_Id"`
 	ProblemID int64 `json:"problem_Id"`
@@ -222,19 +291,36 @@ type ScoreboardProblem struct {
 	Title string `json:"title"`
 }
 
+// ScoreboardCell is the state of a problem for a user in an ICPC contest or a frozen scoreboard
+type ScoreboardCell struct {
+	Solved     bool `json:"solved"`
+	Attempts   int  `json:"attempts"`            // rejected attempts before the accepted one
+	SolvedAt   int  `json:"solved_at,omitempty"` // minutes since start of the contest
+	FirstSolve bool `json:"first_solve,omitempty"`
+	Pending    int  `json:"pending,omitempty"` // attempts which are not judged yet or are hidden by the freeze
+}
+
 type ScoreboardUserStanding struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"user_name"`
-	Scores   []int  `json:"scores"`
+	UserID   int64            `json:"user_id"`
+	Username string           `json:"user_name"`
+	Scores   []int            `json:"scores"`
+	Cells    []ScoreboardCell `json:"cells,omitempty"`   // only in ICPC contests and frozen scoreboards
+	Solved   int              `json:"solved,omitempty"`  // only in ICPC contests
+	Penalty  int              `json:"penalty,omitempty"` // only in ICPC contests, in minutes
+	Virtual  bool             `json:"virtual,omitempty"`
 }
 type ResponseGetContestScoreboard struct {
+	Type     string                   `json:"type"`
+	Frozen   bool                     `json:"frozen,omitempty"`
+	Virtual  bool                     `json:"virtual,omitempty"`
 	Count    int                      `json:"count,omitempty"`
 	Users    []ScoreboardUserStanding `json:"users"`
 	Problems []ScoreboardProblem      `json:"problems"`
 }
 
 type ResponseGetTestcase struct {
-	ID     int64  `json:"id"`
-	Input  string `json:"input"`
-	Output string `json:"output"`
+	ID      int64  `json:"id"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	GroupID int64  `json:"group_id,omitempty"`
 }
@@ -6,6 +6,17 @@ type User struct {
 	EncryptedPassword string
 	Email             string
 	Verified          bool
+	Role              string // one of Roles
+}
+
+// TokenClaims are the claims of the auth tokens. tokens of a login share the SessionID, every refresh token has
+// its own TokenID and Generation is increased by logging out of all sessions of the user
+type TokenClaims struct {
+	UserID     int64
+	Type       string // access or refresh
+	SessionID  string
+	TokenID    string // only set in refresh tokens
+	Generation int64
 }
 
 type ProblemDescription struct {
@@ -21,6 +32,18 @@ type Problem struct {
 	SolvedCount int64
 	Hardness    int64
 	IsPrivate   bool
+	TimeLimit   int64 // milliseconds
+	MemoryLimit int64 // megabytes
+	CheckerType string
+}
+
+// Checker is how outputs of a problem are judged, Epsilon is only used by CheckerFloat
+// and Language and Code only by CheckerCustom and CheckerInteractive
+type Checker struct {
+	Type     string  `json:"type"`
+	Epsilon  float64 `json:"epsilon,omitempty"`
+	Language string  `json:"language,omitempty"`
+	Code     string  `json:"code,omitempty"`
 }
 
 type SubmissionMetadata struct {
@@ -31,38 +54,85 @@ type SubmissionMetadata struct {
 	FileName      string `json:"file_name"`
 	JudgeResultID string `json:"judge_result_id"`
 	Score         int    `json:"score"`
-	Status        string `json:"status"`   // either 'new', 'processing', 'processed'
-	Language      string `json:"language"` // just 'python' for now
+	Status        string `json:"status"`                   // one of Submission* statuses
+	StatusMessage string `json:"status_message,omitempty"` // reason of a judge error
+	TestsDone     int    `json:"tests_done,omitempty"`
+	TestsTotal    int    `json:"tests_total,omitempty"`
+	Language      string `json:"language"` // one of SupportedLanguages
 	IsFinal       bool   `json:"is_final"`
 	Public        bool   `json:"public"`
 	CreatedAT     string `json:"created_at"`
 	ProblemTitle  string `json:"problem_title"`
 }
 
+// Rejudge is a job of judging already judged submissions again, one of ProblemID, ContestID and SubmissionID is set.
+// Judged and Failed only count the submissions which are not taken by a later rejudge
+type Rejudge struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	ProblemID    int64  `json:"problem_id,omitempty"`
+	ContestID    int64  `json:"contest_id,omitempty"`
+	SubmissionID int64  `json:"submission_id,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	Total        int    `json:"total"`
+	Judged       int    `json:"judged"`
+	Failed       int    `json:"failed"` // submissions with a judge error
+}
+
 type Testcase struct {
 	ProblemID      int64  `json:"problem_id"`
 	ID             int64  `json:"id"`
 	Input          string `json:"input,omitempty"`
 	ExpectedOutput string `json:"output,omitempty"`
+	GroupID        int64  `json:"group_id,omitempty"` // zero if the test is not in any group
+}
+
+// TestGroup is a subtask of a problem, its points are given only if every test of it passes
+type TestGroup struct {
+	ID     int64 `json:"id" bson:"id"`
+	Points int   `json:"points" bson:"points"`
 }
 
 type TestResult struct {
-	SubmissionID int64  `json:"submission_id"`
-	TestcaseID   int64  `json:"id"`
-	RunnerOutput string `json:"runner_output"`
-	RunnerError  string `json:"runner_error"`
+	SubmissionID   int64  `json:"submission_id"`
+	TestcaseID     int64  `json:"id"`
+	RunnerOutput   string `json:"runner_output"`
+	RunnerError    string `json:"runner_error"`
+	PeakMemory     int64  `json:"peak_memory"` // in bytes
+	WallTime       int64  `json:"wall_time"`   // in milliseconds
+	CPUTime        int64  `json:"cpu_time"`    // in milliseconds
+	CheckerMessage string `json:"checker_message,omitempty" bson:"checker_message,omitempty"`
+	GroupID        int64  `json:"group_id,omitempty" bson:"group_id,omitempty"`
 	Verdict
 }
 
 type JudgeRequest struct {
 	SubmissionID int64      `json:"submission_id"`
 	Code         string     `json:"code"`
+	Language     string     `json:"language"`
+	TimeLimit    int64      `json:"time_limit"`   // milliseconds, zero means the runner default
+	MemoryLimit  int64      `json:"memory_limit"` // megabytes, zero means the runner default
+	Checker      Checker    `json:"checker"`
 	Testcases    []Testcase `json:"testcases"`
 }
 
+// JudgeProgress is published by runners while they are judging a submission
+type JudgeProgress struct {
+	SubmissionID int64       `json:"submission_id"`
+	Status       string      `json:"status"` // one of Submission* statuses
+	TestsDone    int         `json:"tests_done"`
+	TestsTotal   int         `json:"tests_total"`
+	TestResult   *TestResult `json:"test_result,omitempty"` // the test which was just finished
+	Score        int         `json:"score,omitempty"`       // only when it is judged
+	Message      string      `json:"message,omitempty"`     // reason of a judge error
+}
+
 type JudgeResponse struct {
-	ServerError string       `json:"server_error" bson:"server_error"` // for example, a database failure
-	TestResults []TestResult `json:"test_results" bson:"test_results"` // 'Wrong', 'Success', 'Timelimit', 'Memorylimit'
+	SubmissionID int64        `json:"submission_id" bson:"submission_id"`
+	ServerError  string       `json:"server_error" bson:"server_error"`                       // for example, a database failure
+	CompileError string       `json:"compile_error,omitempty" bson:"compile_error,omitempty"` // compiler output when the build fails
+	TestResults  []TestResult `json:"test_results" bson:"test_results"`                       // 'Wrong', 'Success', 'Timelimit', 'Memorylimit'
+	Groups       []TestGroup  `json:"groups,omitempty" bson:"groups,omitempty"`               // groups of the problem when it was judged
 }
 
 type ContestProblem struct {
@@ -70,10 +140,50 @@ type ContestProblem struct {
 	Title string
 }
 
+// ContestStanding is the state of a problem for a user in a contest, it is updated whenever a result is processed.
+// Username is only set when it is read
+type ContestStanding struct {
+	ContestID          int64
+	UserID             int64
+	Username           string
+	ProblemID          int64
+	Score              int   // best score
+	Attempts           int   // rejected attempts before the first accepted one
+	SolvedSubmissionID int64 // first submission with full score, zero if it is not solved
+	SolvedTime         int64 // unix time of SolvedSubmissionID
+}
+
+// ContestParticipant is a user registered in a contest, StartOffset is the seconds from the start of the contest
+// to the start of a virtual participation and is zero for the users who took the contest itself
+type ContestParticipant struct {
+	UserID      int64
+	Username    string
+	StartOffset int64
+}
+
+// ContestStaff is a user who helps the owner of a contest, Username is only set when it is read
+// ProblemAccess is a user who a private problem is shared with
+type ProblemAccess struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type ContestStaff struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"` // one of ContestRoles
+}
+
 type Contest struct {
 	CreatedBy int64
 	ID        int64
 	Title     string
 	StartTime int64
 	Duration  int
+	Type      string // one of ContestTypes
+	// FreezeTime is when the scoreboard is frozen for users other than the owner, zero if it is never frozen.
+	// hidden submissions are revealed one by one up to RevealedUntil, or all together by Unfrozen
+	FreezeTime    int64
+	Unfrozen      bool
+	RevealedUntil int64
 }
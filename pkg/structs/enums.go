@@ -15,6 +15,7 @@ const (
 	VerdictRuntimeError
 	VerdictUnknown
 	VerdictCompileError
+	VerdictSkipped // an earlier test of the same group failed
 )
 
 func (v Verdict) String() string {
@@ -33,6 +34,8 @@ func (v Verdict) String() string {
 		return "XX"
 	case VerdictCompileError:
 		return "CE"
+	case VerdictSkipped:
+		return "SK"
 	}
 	return "XX"
 }
@@ -53,12 +56,75 @@ func VerdictFromString(s string) Verdict {
 		return VerdictUnknown
 	case "CE":
 		return VerdictCompileError
+	case "SK":
+		return VerdictSkipped
 	}
 	// TODO: safe error handling
 	pkg.Log.Error("unknown verdict", s)
 	return -1
 }
 
+// SupportedLanguages are the languages that runner knows how to compile and run
+var SupportedLanguages = []string{"python", "c", "cpp", "go", "java", "rust"}
+
+// submission statuses through the judge pipeline, 'processing' is only kept for old rows
+const (
+	SubmissionUnprocessed = "unprocessed" // stored but not sent to the judge yet
+	SubmissionQueued      = "queued"
+	SubmissionCompiling   = "compiling"
+	SubmissionRunning     = "running"
+	SubmissionProcessed   = "processed" // judged, results are available
+	SubmissionJudgeError  = "judge_error"
+)
+
+// checker types, an empty type is the same as CheckerExact
+const (
+	CheckerExact           = "exact"
+	CheckerTokens          = "tokens"
+	CheckerFloat           = "float"
+	CheckerCaseInsensitive = "case_insensitive"
+	CheckerCustom          = "custom"
+	CheckerInteractive     = "interactive" // the uploaded program is an interactor which talks to the submission
+)
+
+var CheckerTypes = []string{CheckerExact, CheckerTokens, CheckerFloat, CheckerCaseInsensitive, CheckerCustom, CheckerInteractive}
+
+// contest types select how the scoreboard is ranked, an empty type is the same as ContestTypeScore
+const (
+	ContestTypeScore = "score" // sum of the best scores of problems
+	ContestTypeICPC  = "icpc"  // number of solved problems, then penalty time
+)
+
+var ContestTypes = []string{ContestTypeScore, ContestTypeICPC}
+
+// contest statuses filter the contest lists by time, a contest is running from its start time for its duration in seconds
+const (
+	ContestStatusUpcoming = "upcoming"
+	ContestStatusRunning  = "running"
+	ContestStatusFinished = "finished"
+	ContestStatusStarted  = "started" // running or finished
+)
+
+var ContestStatuses = []string{ContestStatusUpcoming, ContestStatusRunning, ContestStatusFinished, ContestStatusStarted}
+
+// user roles, setters can create problems and contests and admins can change everything
+const (
+	RoleAdmin      = "admin"
+	RoleSetter     = "setter"
+	RoleContestant = "contestant"
+)
+
+var Roles = []string{RoleAdmin, RoleSetter, RoleContestant}
+
+// roles of the staff of a contest besides its owner, co-owners can change the contest like its owner
+// and testers can see and submit to it before the start
+const (
+	ContestRoleCoOwner = "co_owner"
+	ContestRoleTester  = "tester"
+)
+
+var ContestRoles = []string{ContestRoleCoOwner, ContestRoleTester}
+
 type RegistrationStatus int
 
 const (
@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+)
+
+// Checker decides whether the output of a submission on a testcase is accepted.
+// the returned message is shown to the user next to the verdict.
+type Checker interface {
+	Check(input, expected, actual string) (structs.Verdict, string, error)
+	Cleanup() error
+}
+
+// NewChecker creates the checker described in the judge request, caller must call Cleanup on it
+func NewChecker(c structs.Checker, conf configs.SectionRunner) (Checker, error) {
+	switch c.Type {
+	case "", structs.CheckerExact:
+		return exactChecker{}, nil
+	case structs.CheckerTokens:
+		return tokensChecker{}, nil
+	case structs.CheckerCaseInsensitive:
+		return tokensChecker{caseInsensitive: true}, nil
+	case structs.CheckerFloat:
+		eps := c.Epsilon
+		if eps == 0 {
+			eps = DefaultCheckerEpsilon
+		}
+		return floatChecker{epsilon: eps}, nil
+	case structs.CheckerCustom:
+		return newCustomChecker(c, conf)
+	}
+	return nil, errors.WithMessagef(pkg.ErrBadRequest, "unknown checker type %v", c.Type)
+}
+
+// exactChecker compares the whole output, ignoring leading and trailing whitespace
+type exactChecker struct{}
+
+func (exactChecker) Check(_, expected, actual string) (structs.Verdict, string, error) {
+	if strings.TrimSpace(actual) != strings.TrimSpace(expected) {
+		return structs.VerdictWrong, "", nil
+	}
+	return structs.VerdictOK, "", nil
+}
+
+func (exactChecker) Cleanup() error {
+	return nil
+}
+
+// tokensChecker compares outputs token by token, so any amount of whitespace between tokens is accepted
+type tokensChecker struct {
+	caseInsensitive bool
+}
+
+func (t tokensChecker) Check(_, expected, actual string) (structs.Verdict, string, error) {
+	exp, act := strings.Fields(expected), strings.Fields(actual)
+	for i := range exp {
+		if i >= len(act) {
+			return structs.VerdictWrong, "unexpected end of output, expected " + strconv.Itoa(len(exp)) + " tokens", nil
+		}
+		equal := exp[i] == act[i]
+		if t.caseInsensitive {
+			equal = strings.EqualFold(exp[i], act[i])
+		}
+		if !equal {
+			return structs.VerdictWrong, "token " + strconv.Itoa(i+1) + " differs", nil
+		}
+	}
+	if len(act) > len(exp) {
+		return structs.VerdictWrong, "extra tokens in output", nil
+	}
+	return structs.VerdictOK, "", nil
+}
+
+func (tokensChecker) Cleanup() error {
+	return nil
+}
+
+// floatChecker compares outputs token by token, numbers are equal if their absolute or relative error is at most epsilon
+type floatChecker struct {
+	epsilon float64
+}
+
+func (f floatChecker) Check(_, expected, actual string) (structs.Verdict, string, error) {
+	exp, act := strings.Fields(expected), strings.Fields(actual)
+	if len(exp) != len(act) {
+		return structs.VerdictWrong, "expected " + strconv.Itoa(len(exp)) + " tokens, found " + strconv.Itoa(len(act)), nil
+	}
+	for i := range exp {
+		e, errExp := strconv.ParseFloat(exp[i], 64)
+		if errExp != nil {
+			if exp[i] != act[i] {
+				return structs.VerdictWrong, "token " + strconv.Itoa(i+1) + " differs", nil
+			}
+			continue
+		}
+		a, errAct := strconv.ParseFloat(act[i], 64)
+		if errAct != nil || math.IsNaN(a) || math.IsInf(a, 0) {
+			return structs.VerdictWrong, "token " + strconv.Itoa(i+1) + " is not a valid number", nil
+		}
+		diff := math.Abs(a - e)
+		if diff > f.epsilon && diff > f.epsilon*math.Abs(e) {
+			return structs.VerdictWrong, "token " + strconv.Itoa(i+1) + ": expected " + exp[i] + ", found " + act[i], nil
+		}
+	}
+	return structs.VerdictOK, "", nil
+}
+
+func (floatChecker) Cleanup() error {
+	return nil
+}
+
+// customChecker runs a program uploaded by the problem setter, it is called as
+//
+//	checker input.txt answer.txt output.txt
+//
+// and follows testlib exit codes: 0 accepted, 1 wrong answer, 2 presentation error and anything else means the checker failed.
+// whatever it writes to stdout or stderr is used as the message.
+type customChecker struct {
+	d    Runner
+	lang Language
+}
+
+func newCustomChecker(c structs.Checker, conf configs.SectionRunner) (Checker, error) {
+	d, lang, err := prepareProblemProgram(c, conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "checker")
+	}
+	return customChecker{d: d, lang: lang}, nil
+}
+
+func (c customChecker) Check(input, expected, actual string) (structs.Verdict, string, error) {
+	files := map[string]string{
+		checkerInputFile:  input,
+		checkerAnswerFile: expected,
+		checkerOutputFile: actual,
+	}
+	for name, content := range files {
+		if err := c.d.CreateFile(name, strings.NewReader(content)); err != nil {
+			return structs.VerdictUnknown, "", err
+		}
+	}
+
+	var message bytes.Buffer
+	c.d.MemoryLimit(MemoryLimit)
+	c.d.AddressSpaceLimit(c.lang.AddressSpaceLimit(MemoryLimit))
+	c.d.SetMaxProcesses(1)
+	c.d.TimeLimit(CheckerTimeLimit)
+	c.d.Stdin(strings.NewReader(""))
+	c.d.Stdout(&message)
+	c.d.Stderr(&message)
+
+	command := append(append([]string{}, c.lang.RunCmd...), checkerInputFile, checkerAnswerFile, checkerOutputFile)
+	v, err := c.d.Run(command, true)
+	if err != nil {
+		return structs.VerdictUnknown, message.String(), err
+	}
+	v, err = testlibVerdict(v, c.d.Stats().ExitCode)
+	return v, message.String(), errors.WithMessage(err, "checker")
+}
+
+func (c customChecker) Cleanup() error {
+	return c.d.Cleanup()
+}
+
+// prepareProblemProgram compiles a program uploaded by the problem setter, like a checker or an interactor.
+// caller must call Cleanup on the returned runner
+func prepareProblemProgram(c structs.Checker, conf configs.SectionRunner) (Runner, Language, error) {
+	lang, err := GetLanguage(c.Language)
+	if err != nil {
+		return nil, Language{}, err
+	}
+	d, err := Prepare(lang, c.Code, conf)
+	if err != nil {
+		return nil, Language{}, err
+	}
+	verdict, output, err := Compile(d, lang)
+	if err == nil && verdict != structs.VerdictOK {
+		err = errors.New("compilation failed: " + output)
+	}
+	if err != nil {
+		d.Cleanup()
+		return nil, Language{}, err
+	}
+	return d, lang, nil
+}
+
+// testlibVerdict maps how a checker or interactor finished to the verdict of the submission,
+// exit code 0 is accepted, 1 is wrong answer and 2 is presentation error. anything else means it failed itself
+func testlibVerdict(v structs.Verdict, exitCode int) (structs.Verdict, error) {
+	switch v {
+	case structs.VerdictOK:
+		return structs.VerdictOK, nil
+	case structs.VerdictRuntimeError:
+		switch exitCode {
+		case 1, 2:
+			return structs.VerdictWrong, nil
+		}
+	}
+	return structs.VerdictUnknown, errors.Errorf("failed with verdict %v and exit code %v", v, exitCode)
+}
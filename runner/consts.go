@@ -3,7 +3,21 @@ package runner
 import "time"
 
 const (
-	NatsTimeout = time.Minute
-	TimeLimit   = time.Second * 10
-	MemoryLimit = 256 * 1024 * 1024
+	NatsTimeout      = time.Minute
+	TimeLimit        = time.Second * 10
+	MemoryLimit      = 256 * 1024 * 1024
+	CompileTimeLimit = time.Second * 30
+
+	CompileMaxProcesses = 256 // compilers fork and the pids controller counts threads too
+	SandboxInitCommand  = "sandbox-init"
+	LimitExecCommand    = "limit-exec"
+
+	AddressSpaceSlack = 4 // RLIMIT_AS is this times the memory limit, see resourceLimiter
+
+	CheckerTimeLimit      = time.Second * 10
+	DefaultCheckerEpsilon = 1e-6
+
+	checkerInputFile  = "input.txt"
+	checkerAnswerFile = "answer.txt"
+	checkerOutputFile = "output.txt"
 )
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ocontest/backend/pkg/configs"
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+)
+
+// Interactor is the program of an interactive problem which talks to the submission, it is called as
+//
+//	interactor input.txt answer.txt
+//
+// with its stdout connected to stdin of the submission and the other way around.
+// its exit code decides the verdict the same way as a custom checker.
+type Interactor struct {
+	d    Runner
+	lang Language
+}
+
+// NewInteractor compiles the interactor described in the judge request, caller must call Cleanup on it
+func NewInteractor(c structs.Checker, conf configs.SectionRunner) (*Interactor, error) {
+	d, lang, err := prepareProblemProgram(c, conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "interactor")
+	}
+	return &Interactor{d: d, lang: lang}, nil
+}
+
+func (i *Interactor) Cleanup() error {
+	return i.d.Cleanup()
+}
+
+// RunInteractive runs an already compiled submission together with the interactor on a single test.
+// both processes have the time limit, the returned message is what the interactor wrote to stderr
+func RunInteractive(d Runner, lang Language, interactor *Interactor, timeLimit time.Duration, memoryLimit int, input, answer string, stderr io.Writer) (structs.Verdict, string, RunStats, error) {
+	files := map[string]string{
+		checkerInputFile:  input,
+		checkerAnswerFile: answer,
+	}
+	for name, content := range files {
+		if err := interactor.d.CreateFile(name, strings.NewReader(content)); err != nil {
+			return structs.VerdictUnknown, "", RunStats{}, err
+		}
+	}
+
+	toSubmission, fromInteractor, err := os.Pipe()
+	if err != nil {
+		return structs.VerdictUnknown, "", RunStats{}, err
+	}
+	toInteractor, fromSubmission, err := os.Pipe()
+	if err != nil {
+		toSubmission.Close()
+		fromInteractor.Close()
+		return structs.VerdictUnknown, "", RunStats{}, err
+	}
+
+	var message bytes.Buffer
+	interactor.d.MemoryLimit(MemoryLimit)
+	interactor.d.AddressSpaceLimit(interactor.lang.AddressSpaceLimit(MemoryLimit))
+	interactor.d.SetMaxProcesses(1)
+	interactor.d.TimeLimit(timeLimit)
+	interactor.d.Stdin(toInteractor)
+	interactor.d.Stdout(fromInteractor)
+	interactor.d.Stderr(&message)
+
+	d.MemoryLimit(memoryLimit)
+	d.AddressSpaceLimit(lang.AddressSpaceLimit(memoryLimit))
+	d.SetMaxProcesses(1)
+	d.TimeLimit(timeLimit)
+	d.Stdin(toSubmission)
+	d.Stdout(fromSubmission)
+	d.Stderr(stderr)
+
+	var (
+		wg                      sync.WaitGroup
+		interactorV, submission structs.Verdict
+		interactorErr, runErr   error
+	)
+	// our copies of the pipe ends are closed as soon as a process exits,
+	// so the other one sees EOF instead of waiting until its time limit
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		command := append(append([]string{}, interactor.lang.RunCmd...), checkerInputFile, checkerAnswerFile)
+		interactorV, interactorErr = interactor.d.Run(command, true)
+		toInteractor.Close()
+		fromInteractor.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		submission, runErr = d.Run(lang.RunCmd, false)
+		toSubmission.Close()
+		fromSubmission.Close()
+	}()
+	wg.Wait()
+
+	stats := d.Stats()
+	if runErr != nil {
+		return structs.VerdictUnknown, message.String(), stats, runErr
+	}
+	if interactorErr != nil {
+		return structs.VerdictUnknown, message.String(), stats, errors.WithMessage(interactorErr, "interactor")
+	}
+
+	switch submission {
+	case structs.VerdictTimeLimit, structs.VerdictMemoryLimit:
+		return submission, message.String(), stats, nil
+	}
+	v, err := testlibVerdict(interactorV, interactor.d.Stats().ExitCode)
+	if err != nil {
+		return structs.VerdictUnknown, message.String(), stats, errors.WithMessage(err, "interactor")
+	}
+	if v == structs.VerdictOK && submission != structs.VerdictOK {
+		// the interactor got what it wanted but the submission crashed afterwards
+		v = submission
+	}
+	return v, message.String(), stats, nil
+}
@@ -0,0 +1,331 @@
+//go:build linux && (amd64 || arm64)
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/ocontest/backend/pkg/structs"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// directories of the host which are visible read-only inside the sandbox
+var readOnlyDirs = []string{"/bin", "/sbin", "/lib", "/lib32", "/lib64", "/usr", "/etc"}
+
+var sandboxDevices = []string{"null", "zero", "random", "urandom"}
+
+// sandboxEnv is the whole environment of sandboxed processes, runner's own env holds secrets
+var sandboxEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/usr/local/go/bin",
+	"HOME=/box",
+	"TMPDIR=/tmp",
+	"GOCACHE=/tmp/gocache",
+	"LANG=C.UTF-8",
+}
+
+const (
+	secbitNoRoot       = 1 << 0
+	secbitNoRootLocked = 1 << 1
+)
+
+// sandboxConfig is passed from Isolated.Run to SandboxInit
+type sandboxConfig struct {
+	Root         string   `json:"root"`
+	Box          string   `json:"box"`
+	NoFork       bool     `json:"no_fork"`
+	AddressSpace int64    `json:"address_space"`
+	Command      []string `json:"command"`
+}
+
+// Isolated runs each process in new user, mount, pid, network, ipc and uts namespaces.
+// the process only sees a read-only view of system directories, its own box directory and a private /tmp.
+// a seccomp filter blocks sockets and other syscalls a submission has no use for.
+// it works by re-executing the current binary with SandboxInitCommand, which sets up the namespaces from
+// inside and then executes the actual command.
+type Isolated struct {
+	Dummy
+	rootDir string
+}
+
+func NewIsolated() (*Isolated, error) {
+	s := &Isolated{}
+	return s, s.Init()
+}
+
+func (s *Isolated) Init() error {
+	var err error
+	if s.tmpdir, err = os.MkdirTemp("", "isolatedsandbox"); err != nil {
+		return err
+	}
+
+	s.workingDir = filepath.Join(s.tmpdir, "box")
+	s.rootDir = filepath.Join(s.tmpdir, "root")
+	for _, dir := range []string{s.workingDir, s.rootDir} {
+		if err = os.Mkdir(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Isolated) Run(command []string, needStatus bool) (structs.Verdict, error) {
+	if len(command) == 0 {
+		return structs.VerdictUnknown, errors.New("empty command")
+	}
+
+	conf := sandboxConfig{
+		Root:    s.rootDir,
+		Box:     s.workingDir,
+		NoFork:  s.maxProcs == 1,
+		Command: command,
+	}
+	// the init process is a go program which reserves a lot of address space itself,
+	// so RLIMIT_AS is applied by SandboxInit right before exec instead of by the limiter
+	if s.cgroupRoot == "" {
+		conf.AddressSpace = s.asl
+	}
+	confData, err := json.Marshal(conf)
+	if err != nil {
+		return structs.VerdictUnknown, err
+	}
+
+	// SandboxInit reports setup failures on this pipe, it is closed on exec otherwise
+	setupReader, setupWriter, err := os.Pipe()
+	if err != nil {
+		return structs.VerdictUnknown, err
+	}
+	defer setupReader.Close()
+
+	cmd := exec.Command("/proc/self/exe", SandboxInitCommand, string(confData))
+	cmd.Stdin = s.stdin
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+	cmd.Env = sandboxEnv
+	cmd.ExtraFiles = []*os.File{setupWriter}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	v, err := s.execute(cmd, 0)
+	setupWriter.Close()
+
+	setupErr, readErr := io.ReadAll(setupReader)
+	if readErr != nil {
+		return structs.VerdictUnknown, readErr
+	}
+	if len(setupErr) != 0 {
+		return structs.VerdictUnknown, errors.New("sandbox setup failed: " + string(setupErr))
+	}
+	return v, err
+}
+
+// SandboxInit is the entry point of SandboxInitCommand, it runs inside the new namespaces and never returns
+func SandboxInit(args []string) {
+	// capabilities and no_new_privs are per thread, everything must happen on the thread that calls exec
+	runtime.LockOSThread()
+
+	setupPipe := os.NewFile(3, "setup")
+	fail := func(err error) {
+		fmt.Fprint(setupPipe, err.Error())
+		os.Exit(1)
+	}
+
+	if len(args) != 1 {
+		fail(errors.New("sandbox init expects exactly one argument"))
+	}
+	var conf sandboxConfig
+	if err := json.Unmarshal([]byte(args[0]), &conf); err != nil {
+		fail(errors.Wrap(err, "invalid sandbox config"))
+	}
+	if len(conf.Command) == 0 {
+		fail(errors.New("empty command"))
+	}
+
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		fail(errors.Wrap(err, "couldn't set hostname"))
+	}
+	if err := setupMounts(conf); err != nil {
+		fail(err)
+	}
+
+	path, err := exec.LookPath(conf.Command[0])
+	if err != nil {
+		fail(errors.Wrap(err, "couldn't find command"))
+	}
+
+	if err := dropCapabilities(); err != nil {
+		fail(err)
+	}
+	if err := installSeccomp(conf.NoFork); err != nil {
+		fail(err)
+	}
+	if conf.AddressSpace > 0 {
+		limit := unix.Rlimit{Cur: uint64(conf.AddressSpace), Max: uint64(conf.AddressSpace)}
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &limit); err != nil {
+			fail(errors.Wrap(err, "couldn't set address space limit"))
+		}
+	}
+
+	unix.CloseOnExec(int(setupPipe.Fd()))
+	err = syscall.Exec(path, conf.Command, os.Environ())
+	fail(errors.Wrap(err, "couldn't exec command"))
+}
+
+func setupMounts(conf sandboxConfig) error {
+	// nothing done here should propagate back to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return errors.Wrap(err, "couldn't make mounts private")
+	}
+	if err := unix.Mount("tmpfs", conf.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=16m,mode=755"); err != nil {
+		return errors.Wrap(err, "couldn't mount sandbox root")
+	}
+
+	for _, dir := range readOnlyDirs {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(conf.Root, dir)
+		// merged /usr systems have /bin -> usr/bin and so on
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(dir)
+			if err != nil {
+				return err
+			}
+			if err = os.Symlink(link, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = bindMount(dir, target, true); err != nil {
+			return err
+		}
+	}
+
+	if err := bindMount(conf.Box, filepath.Join(conf.Root, "box"), false); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(conf.Root, "tmp")
+	if err := os.Mkdir(tmp, 0777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=256m,mode=1777"); err != nil {
+		return errors.Wrap(err, "couldn't mount /tmp")
+	}
+
+	// proc can't be mounted when the host hides parts of it (nested containers), programs mostly work without it
+	proc := filepath.Join(conf.Root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return err
+	}
+	_ = unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	dev := filepath.Join(conf.Root, "dev")
+	if err := os.Mkdir(dev, 0755); err != nil {
+		return err
+	}
+	for _, name := range sandboxDevices {
+		target := filepath.Join(dev, name)
+		if err := os.WriteFile(target, nil, 0666); err != nil {
+			return err
+		}
+		if err := unix.Mount(filepath.Join("/dev", name), target, "", unix.MS_BIND, ""); err != nil {
+			return errors.Wrap(err, "couldn't bind /dev/"+name)
+		}
+	}
+
+	oldRoot := filepath.Join(conf.Root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(conf.Root, oldRoot); err != nil {
+		return errors.Wrap(err, "couldn't pivot root")
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return errors.Wrap(err, "couldn't unmount old root")
+	}
+	if err := os.Remove("/.oldroot"); err != nil {
+		return err
+	}
+
+	// the sandbox root itself is not needed to be writable anymore
+	if err := unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return errors.Wrap(err, "couldn't make sandbox root read-only")
+	}
+	return unix.Chdir("/box")
+}
+
+func bindMount(source, target string, readOnly bool) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := unix.Mount(source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return errors.Wrapf(err, "couldn't bind %v", source)
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	// flags of the original mount are locked inside a user namespace, they must be kept on remount
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return err
+	}
+	lockedFlags := map[int64]uintptr{
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+		unix.ST_RDONLY:     unix.MS_RDONLY,
+	}
+	for stFlag, msFlag := range lockedFlags {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return errors.Wrapf(err, "couldn't remount %v", target)
+	}
+	return nil
+}
+
+// dropCapabilities makes sure the command runs without any capability, even though it is root in its namespace
+func dropCapabilities() error {
+	if err := unix.Prctl(unix.PR_SET_SECUREBITS, secbitNoRoot|secbitNoRootLocked, 0, 0, 0); err != nil {
+		return errors.Wrap(err, "couldn't set securebits")
+	}
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+			return errors.Wrap(err, "couldn't drop bounding set")
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return errors.Wrap(err, "couldn't clear ambient capabilities")
+	}
+
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return errors.Wrap(err, "couldn't drop capabilities")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !(linux && (amd64 || arm64))
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errIsolatedNotSupported = errors.New("isolated runner is only supported on linux amd64 and arm64")
+
+type Isolated struct {
+	Dummy
+}
+
+func NewIsolated() (*Isolated, error) {
+	return nil, errIsolatedNotSupported
+}
+
+func SandboxInit(args []string) {
+	fmt.Fprintln(os.Stderr, errIsolatedNotSupported)
+	os.Exit(1)
+}
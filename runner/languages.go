@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"github.com/ocontest/backend/pkg"
+	"github.com/pkg/errors"
+)
+
+// Language describes how a submission written in some language is built and executed.
+// commands are run inside the sandbox working directory.
+type Language struct {
+	Name       string
+	SourceFile string
+	CompileCmd []string // empty for interpreted languages
+	RunCmd     []string
+
+	// UnlimitedAddressSpace is for runtimes like the jvm which reserve a lot more virtual memory than they use
+	UnlimitedAddressSpace bool
+}
+
+func (l Language) NeedsCompile() bool {
+	return len(l.CompileCmd) != 0
+}
+
+// AddressSpaceLimit is the RLIMIT_AS used for this language when cgroups are not available
+func (l Language) AddressSpaceLimit(memoryLimit int) int64 {
+	if l.UnlimitedAddressSpace {
+		return 0
+	}
+	return int64(memoryLimit) * AddressSpaceSlack
+}
+
+var languages = map[string]Language{
+	"python": {
+		Name:       "python",
+		SourceFile: "main.py",
+		RunCmd:     []string{"python3", "main.py"},
+	},
+	"c": {
+		Name:       "c",
+		SourceFile: "main.c",
+		CompileCmd: []string{"gcc", "-O2", "-std=gnu11", "-o", "main", "main.c", "-lm"},
+		RunCmd:     []string{"./main"},
+	},
+	"cpp": {
+		Name:       "cpp",
+		SourceFile: "main.cpp",
+		CompileCmd: []string{"g++", "-O2", "-std=gnu++17", "-o", "main", "main.cpp"},
+		RunCmd:     []string{"./main"},
+	},
+	"go": {
+		Name:       "go",
+		SourceFile: "main.go",
+		CompileCmd: []string{"go", "build", "-o", "main", "main.go"},
+		RunCmd:     []string{"./main"},
+	},
+	"java": {
+		Name:       "java",
+		SourceFile: "Main.java",
+		CompileCmd: []string{"javac", "Main.java"},
+		RunCmd:     []string{"java", "Main"},
+
+		UnlimitedAddressSpace: true,
+	},
+	"rust": {
+		Name:       "rust",
+		SourceFile: "main.rs",
+		CompileCmd: []string{"rustc", "-O", "-o", "main", "main.rs"},
+		RunCmd:     []string{"./main"},
+	},
+}
+
+func GetLanguage(name string) (Language, error) {
+	lang, exists := languages[name]
+	if !exists {
+		return Language{}, errors.WithMessagef(pkg.ErrBadRequest, "language %v is not supported", name)
+	}
+	return lang, nil
+}
@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/ocontest/backend/pkg"
+	"golang.org/x/sys/unix"
+)
+
+var cgroupCounter atomic.Int64
+
+// resourceLimiter enforces memory and process count limits on a single process.
+// it uses a child of cgroupRoot when it is a usable cgroup v2 directory, otherwise it falls back to RLIMIT_AS,
+// which is set before exec by running the command through LimitExecCommand.
+// RLIMIT_AS is set to addressSpace which should be looser than limit, because a failed allocation looks like a
+// runtime error. the ML verdict then comes from comparing the peak rss with limit after the process exits.
+// the process count is only enforced with a cgroup, note that the pids controller counts threads too.
+type resourceLimiter struct {
+	limit        int64
+	addressSpace int64
+	processes    int
+	cgroup       string
+	cgroupFD     int
+}
+
+func newResourceLimiter(limit, addressSpace int64, processes int, cgroupRoot string) *resourceLimiter {
+	m := &resourceLimiter{limit: limit, addressSpace: addressSpace, processes: processes, cgroupFD: -1}
+	if (limit <= 0 && processes <= 1) || cgroupRoot == "" {
+		return m
+	}
+
+	controllers, err := os.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	if err != nil {
+		pkg.Log.Warning("couldn't read controllers of cgroup ", cgroupRoot, ", falling back to rlimit: ", err)
+		return m
+	}
+	available := strings.Fields(string(controllers))
+	if (limit > 0 && !slices.Contains(available, "memory")) || (processes > 1 && !slices.Contains(available, "pids")) {
+		pkg.Log.Warning("needed controllers are not available in cgroup ", cgroupRoot, ", falling back to rlimit")
+		return m
+	}
+
+	dir := filepath.Join(cgroupRoot, fmt.Sprintf("run-%d-%d", os.Getpid(), cgroupCounter.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		pkg.Log.Warning("couldn't create cgroup, falling back to rlimit: ", err)
+		return m
+	}
+	if limit > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(limit, 10)), 0644); err != nil {
+			pkg.Log.Warning("couldn't set cgroup memory.max, falling back to rlimit: ", err)
+			os.Remove(dir)
+			return m
+		}
+		// swap may not be enabled on the host, in that case there is nothing to limit
+		_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+	}
+	if processes > 1 {
+		if err := os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.Itoa(processes)), 0644); err != nil {
+			pkg.Log.Warning("couldn't set cgroup pids.max, falling back to rlimit: ", err)
+			os.Remove(dir)
+			return m
+		}
+	}
+
+	fd, err := syscall.Open(dir, unix.O_PATH|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		pkg.Log.Warning("couldn't open cgroup directory, falling back to rlimit: ", err)
+		os.Remove(dir)
+		return m
+	}
+
+	m.cgroup = dir
+	m.cgroupFD = fd
+	return m
+}
+
+func (m *resourceLimiter) usesCgroup() bool {
+	return m.cgroup != ""
+}
+
+// prepare must be called before starting cmd
+func (m *resourceLimiter) prepare(cmd *exec.Cmd) {
+	if !m.usesCgroup() {
+		if m.addressSpace > 0 {
+			// the limit must be in place before the command runs any of its own code
+			cmd.Args = append([]string{"/proc/self/exe", LimitExecCommand, strconv.FormatInt(m.addressSpace, 10), cmd.Path}, cmd.Args...)
+			cmd.Path = "/proc/self/exe"
+		}
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = m.cgroupFD
+}
+
+// LimitExec is the entry point of LimitExecCommand, its arguments are the address space limit in bytes, the path of
+// the command and the arguments of the command including its name. it never returns
+func LimitExec(args []string) {
+	if len(args) < 3 {
+		fmt.Fprintln(os.Stderr, "limit exec expects the limit, the path and the arguments of the command")
+		os.Exit(1)
+	}
+	addressSpace, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid address space limit: ", err)
+		os.Exit(1)
+	}
+
+	limit := unix.Rlimit{Cur: addressSpace, Max: addressSpace}
+	if err := unix.Setrlimit(unix.RLIMIT_AS, &limit); err != nil {
+		fmt.Fprintln(os.Stderr, "couldn't set address space limit: ", err)
+		os.Exit(1)
+	}
+	err = syscall.Exec(args[1], args[2:], os.Environ())
+	fmt.Fprintln(os.Stderr, "couldn't exec command: ", err)
+	os.Exit(1)
+}
+
+// peak returns the peak memory usage of the finished process in bytes
+func (m *resourceLimiter) peak(state *os.ProcessState) int64 {
+	if m.usesCgroup() {
+		if data, err := os.ReadFile(filepath.Join(m.cgroup, "memory.peak")); err == nil {
+			if v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+				return v
+			}
+		}
+	}
+	if state == nil {
+		return 0
+	}
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return usage.Maxrss * 1024 // linux reports it in kilobytes
+	}
+	return 0
+}
+
+// memoryExceeded reports whether the process went over the memory limit
+func (m *resourceLimiter) memoryExceeded(state *os.ProcessState) bool {
+	if m.limit <= 0 {
+		return false
+	}
+	if m.usesCgroup() && m.oomKilled() {
+		return true
+	}
+	return m.peak(state) > m.limit
+}
+
+func (m *resourceLimiter) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(m.cgroup, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *resourceLimiter) cleanup() {
+	if !m.usesCgroup() {
+		return
+	}
+	// kill anything the submission may have left behind, otherwise the cgroup can't be removed
+	_ = os.WriteFile(filepath.Join(m.cgroup, "cgroup.kill"), []byte("1"), 0644)
+	syscall.Close(m.cgroupFD)
+	if err := os.Remove(m.cgroup); err != nil {
+		pkg.Log.Warning("couldn't remove cgroup ", m.cgroup, ": ", err)
+	}
+}
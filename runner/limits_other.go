@@ -0,0 +1,33 @@
+//go:build !linux
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// resourceLimiter can't enforce or measure anything outside linux
+type resourceLimiter struct{}
+
+func newResourceLimiter(limit, addressSpace int64, processes int, cgroupRoot string) *resourceLimiter {
+	return &resourceLimiter{}
+}
+
+func (m *resourceLimiter) prepare(cmd *exec.Cmd) {}
+
+func LimitExec(args []string) {
+	fmt.Fprintln(os.Stderr, "limit exec is only supported on linux")
+	os.Exit(1)
+}
+
+func (m *resourceLimiter) peak(state *os.ProcessState) int64 {
+	return 0
+}
+
+func (m *resourceLimiter) memoryExceeded(state *os.ProcessState) bool {
+	return false
+}
+
+func (m *resourceLimiter) cleanup() {}
@@ -3,6 +3,7 @@ package runner
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/nats-io/nats.go"
 	"github.com/ocontest/backend/internal/judge"
 	"github.com/ocontest/backend/pkg"
@@ -11,7 +12,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"log"
-	"strings"
+	"time"
 )
 
 type RunnerScheduler interface {
@@ -20,16 +21,18 @@ type RunnerScheduler interface {
 }
 
 type RunnerSchedulerImp struct {
-	queue judge.JudgeQueue
+	queue  judge.JudgeQueue
+	config configs.SectionRunner
 }
 
-func NewRunnerScheduler(natsConfig configs.SectionNats) (RunnerScheduler, error) {
-	queue, err := judge.NewJudgeQueue(natsConfig)
+func NewRunnerScheduler(c configs.SectionJudge) (RunnerScheduler, error) {
+	queue, err := judge.NewJudgeQueue(c.Nats)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return RunnerSchedulerImp{
-		queue: queue,
+		queue:  queue,
+		config: c.Runner,
 	}, nil
 }
 
@@ -39,18 +42,36 @@ func (r RunnerSchedulerImp) StartListen() {
 		log.Fatal("couldn't subscribe", err)
 	}
 
+	failures := 0
 	for {
-		msg, err := sub.NextMsg(NatsTimeout)
+		msgs, err := sub.Fetch(1, nats.MaxWait(NatsTimeout))
 		if errors.Is(err, nats.ErrTimeout) {
+			failures = 0
+			continue
+		}
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			log.Fatal("connection of judge queue is closed: ", err)
+		}
+		if errors.Is(err, nats.ErrConsumerDeleted) {
+			pkg.Log.Error("consumer of judge queue is deleted, subscribing again")
+			_ = sub.Unsubscribe()
+			if sub, err = r.queue.Subscribe(); err != nil {
+				log.Fatal("couldn't subscribe", err)
+			}
 			continue
 		}
 		if err != nil {
 			pkg.Log.Error("error on getting message from queue: ", err)
+			time.Sleep(judge.FetchRetryDelay(failures))
+			failures++
 			continue
 		}
+		failures = 0
 
-		pkg.Log.Debug("got msg from nats")
-		r.ProcessCode(msg)
+		for _, msg := range msgs {
+			pkg.Log.Debug("got msg from nats")
+			r.ProcessCode(msg)
+		}
 	}
 }
 
@@ -61,23 +82,161 @@ func (r RunnerSchedulerImp) ProcessCode(msg *nats.Msg) {
 	})
 
 	var task structs.JudgeRequest
-	var resp structs.JudgeResponse
 
 	err := json.Unmarshal(msg.Data, &task)
 	if err != nil {
-		pkg.Log.Error("error on unmarshal message: ", err)
-		msg.Respond([]byte("error on unmarshal message"))
+		logger.Error("error on unmarshal message: ", err)
+		// the submission id is still set when only other fields are invalid
+		r.giveUp(logger, msg, task.SubmissionID, "invalid judge request: "+err.Error())
+		return
 	}
 	logger.Debug("Recieved task ", task.SubmissionID, " number of tests:", len(task.Testcases))
+
+	// a task which failed on all of the other deliveries, for example by crashing the runner, is not judged again
+	delivered, last := r.queue.Delivery(msg)
+	if last && delivered > 1 {
+		r.giveUp(logger, msg, task.SubmissionID, fmt.Sprintf("judging failed %d times", delivered-1))
+		return
+	}
+
+	// judging may take longer than ack wait of the queue, so keep telling it we are still working
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(r.queue.AckWait() / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					logger.Warning("error on reporting progress of judge task: ", err)
+				}
+			}
+		}
+	}()
+
+	resp := r.judgeTask(logger, task)
+	resp.SubmissionID = task.SubmissionID
+
+	err = r.queue.PublishResult(resp)
+	if err != nil {
+		logger.Error("error on publishing judge result: ", err)
+		if last {
+			r.giveUp(logger, msg, task.SubmissionID, "couldn't publish judge result: "+err.Error())
+			return
+		}
+		msg.Nak()
+		return
+	}
+	err = msg.Ack()
+	if err != nil {
+		logger.Error("error on ack judge task: ", err)
+	}
+}
+
+// giveUp publishes a judge error result with the reason and terminates the task, so the submission isn't left waiting.
+// the task is retried if the result can't be published and it has deliveries left
+func (r RunnerSchedulerImp) giveUp(logger *logrus.Entry, msg *nats.Msg, submissionID int64, reason string) {
+	logger.Error("giving up judge task of submission ", submissionID, ": ", reason)
+	if submissionID == 0 {
+		msg.Term()
+		return
+	}
+
+	err := r.queue.PublishResult(structs.JudgeResponse{SubmissionID: submissionID, ServerError: reason})
+	if err != nil {
+		logger.Error("error on publishing judge error result: ", err)
+		if _, last := r.queue.Delivery(msg); !last {
+			msg.Nak()
+			return
+		}
+	}
+	msg.Term()
+}
+
+func (r RunnerSchedulerImp) judgeTask(logger *logrus.Entry, task structs.JudgeRequest) (resp structs.JudgeResponse) {
 	resp.TestResults = make([]structs.TestResult, len(task.Testcases))
 	for ind := range task.Testcases {
-		testCase := task.Testcases[ind]
 		resp.TestResults[ind].SubmissionID = task.SubmissionID
-		resp.TestResults[ind].TestcaseID = testCase.ID
+		resp.TestResults[ind].TestcaseID = task.Testcases[ind].ID
+		resp.TestResults[ind].GroupID = task.Testcases[ind].GroupID
+	}
+
+	lang, err := GetLanguage(task.Language)
+	if err != nil {
+		logger.Error("error on getting language: ", err)
+		resp.ServerError = err.Error()
+		return
+	}
+
+	d, err := Prepare(lang, task.Code, r.config)
+	if err != nil {
+		logger.Error("error on preparing runner: ", err)
+		resp.ServerError = err.Error()
+		return
+	}
+	defer func() {
+		if err := d.Cleanup(); err != nil {
+			logger.Warning("error on doing cleanup of runner: ", err)
+		}
+	}()
+
+	r.reportProgress(logger, task, structs.SubmissionCompiling, 0, nil)
+	verdict, compileOutput, err := Compile(d, lang)
+	if err != nil {
+		logger.Error("error on compiling code: ", err)
+		resp.ServerError = err.Error()
+		return
+	}
+	if verdict == structs.VerdictCompileError {
+		resp.CompileError = compileOutput
+		for ind := range resp.TestResults {
+			resp.TestResults[ind].Verdict = structs.VerdictCompileError
+			resp.TestResults[ind].RunnerError = compileOutput
+		}
+		return
+	}
+
+	timeLimit, memoryLimit := TimeLimit, MemoryLimit
+	if task.TimeLimit != 0 {
+		timeLimit = time.Duration(task.TimeLimit) * time.Millisecond
+	}
+	if task.MemoryLimit != 0 {
+		memoryLimit = int(task.MemoryLimit) * 1024 * 1024
+	}
+
+	if task.Checker.Type == structs.CheckerInteractive {
+		r.judgeInteractive(logger, task, d, lang, timeLimit, memoryLimit, &resp)
+		return
+	}
+
+	checker, err := NewChecker(task.Checker, r.config)
+	if err != nil {
+		logger.Error("error on creating checker: ", err)
+		resp.ServerError = err.Error()
+		return
+	}
+	defer func() {
+		if err := checker.Cleanup(); err != nil {
+			logger.Warning("error on doing cleanup of checker: ", err)
+		}
+	}()
+
+	r.reportProgress(logger, task, structs.SubmissionRunning, 0, nil)
+	failedGroups := make(map[int64]bool)
+	for ind := range task.Testcases {
+		testCase := task.Testcases[ind]
+		if failedGroups[testCase.GroupID] {
+			resp.TestResults[ind].Verdict = structs.VerdictSkipped
+			r.reportProgress(logger, task, structs.SubmissionRunning, ind+1, &resp.TestResults[ind])
+			continue
+		}
 
 		input := bytes.NewReader([]byte(testCase.Input))
 		var output, stderr bytes.Buffer
-		verdict, err := RunTask(TimeLimit, MemoryLimit, task.Code, input, &output, &stderr)
+		verdict, stats, err := RunTask(d, lang, timeLimit, memoryLimit, input, &output, &stderr)
 		if err != nil {
 			logger.Error("error on running code: ", err)
 			verdict = structs.VerdictUnknown
@@ -90,36 +249,90 @@ func (r RunnerSchedulerImp) ProcessCode(msg *nats.Msg) {
 		}
 		resp.TestResults[ind].RunnerError = stderrStr
 		resp.TestResults[ind].RunnerOutput = outputStr
+		resp.TestResults[ind].Verdict = verdict
+		resp.TestResults[ind].PeakMemory = stats.PeakMemory
+		resp.TestResults[ind].WallTime = stats.WallTime.Milliseconds()
+		resp.TestResults[ind].CPUTime = stats.CPUTime.Milliseconds()
 
-		if verdict != structs.VerdictOK {
-			continue
-		}
 		if verdict == structs.VerdictOK {
-			if r.checkOutput(outputStr, task.Testcases[ind].ExpectedOutput) {
-				resp.TestResults[ind].Verdict = structs.VerdictOK
-			} else {
-				resp.TestResults[ind].Verdict = structs.VerdictWrong
+			var message string
+			verdict, message, err = checker.Check(testCase.Input, testCase.ExpectedOutput, outputStr)
+			if err != nil {
+				logger.Error("error on checking output: ", err)
+				resp.ServerError = err.Error()
 			}
+			resp.TestResults[ind].Verdict = verdict
+			resp.TestResults[ind].CheckerMessage = message
 		}
-
+		if verdict != structs.VerdictOK && testCase.GroupID != 0 {
+			failedGroups[testCase.GroupID] = true
+		}
+		r.reportProgress(logger, task, structs.SubmissionRunning, ind+1, &resp.TestResults[ind])
 	}
+	return
+}
 
-	respData, err := json.Marshal(resp)
+// judgeInteractive runs every test of an interactive problem, the submission talks to the interactor instead of reading the input
+func (r RunnerSchedulerImp) judgeInteractive(logger *logrus.Entry, task structs.JudgeRequest, d Runner, lang Language, timeLimit time.Duration, memoryLimit int, resp *structs.JudgeResponse) {
+	interactor, err := NewInteractor(task.Checker, r.config)
 	if err != nil {
-		errorMessage := "error on json marshalling response"
-		pkg.Log.Error(errorMessage)
-		respData = []byte(errorMessage)
-	}
-	err = msg.Respond(respData)
-	if err != nil {
-		pkg.Log.Error("error on respond to judge task", err)
+		logger.Error("error on creating interactor: ", err)
+		resp.ServerError = err.Error()
+		return
 	}
+	defer func() {
+		if err := interactor.Cleanup(); err != nil {
+			logger.Warning("error on doing cleanup of interactor: ", err)
+		}
+	}()
 
-}
+	r.reportProgress(logger, task, structs.SubmissionRunning, 0, nil)
+	failedGroups := make(map[int64]bool)
+	for ind := range task.Testcases {
+		testCase := task.Testcases[ind]
+		if failedGroups[testCase.GroupID] {
+			resp.TestResults[ind].Verdict = structs.VerdictSkipped
+			r.reportProgress(logger, task, structs.SubmissionRunning, ind+1, &resp.TestResults[ind])
+			continue
+		}
 
-func (r RunnerSchedulerImp) checkOutput(actual, expected string) bool {
-	actual = strings.TrimSpace(actual)
-	expected = strings.TrimSpace(expected)
-	return actual == expected
+		var stderr bytes.Buffer
+		verdict, message, stats, err := RunInteractive(d, lang, interactor, timeLimit, memoryLimit, testCase.Input, testCase.ExpectedOutput, &stderr)
+		if err != nil {
+			logger.Error("error on running interactive code: ", err)
+			resp.ServerError = err.Error()
+		}
+		resp.TestResults[ind].RunnerError = stderr.String()
+		resp.TestResults[ind].CheckerMessage = message
+		resp.TestResults[ind].Verdict = verdict
+		resp.TestResults[ind].PeakMemory = stats.PeakMemory
+		resp.TestResults[ind].WallTime = stats.WallTime.Milliseconds()
+		resp.TestResults[ind].CPUTime = stats.CPUTime.Milliseconds()
+
+		if verdict != structs.VerdictOK && testCase.GroupID != 0 {
+			failedGroups[testCase.GroupID] = true
+		}
+		r.reportProgress(logger, task, structs.SubmissionRunning, ind+1, &resp.TestResults[ind])
+	}
+}
 
+// reportProgress tells the backend what is being done on the task and the result of the test which is just finished if any.
+// failures are only logged since judging can go on without it
+func (r RunnerSchedulerImp) reportProgress(logger *logrus.Entry, task structs.JudgeRequest, status string, testsDone int, result *structs.TestResult) {
+	progress := structs.JudgeProgress{
+		SubmissionID: task.SubmissionID,
+		Status:       status,
+		TestsDone:    testsDone,
+		TestsTotal:   len(task.Testcases),
+	}
+	if result != nil {
+		// output of the submission may be large and is not needed for showing progress
+		progress.TestResult = &structs.TestResult{}
+		*progress.TestResult = *result
+		progress.TestResult.RunnerOutput = ""
+	}
+	err := r.queue.PublishProgress(progress)
+	if err != nil {
+		logger.Warning("error on reporting progress: ", err)
+	}
 }
@@ -3,7 +3,9 @@ package runner
 
 import (
 	"bytes"
+	"errors"
 	"github.com/ocontest/backend/pkg"
+	"github.com/ocontest/backend/pkg/configs"
 	"github.com/ocontest/backend/pkg/structs"
 	"io"
 	"io/ioutil"
@@ -16,14 +18,63 @@ import (
 )
 
 type Runner interface {
+	Id() string
+	Init() error
+	Pwd() string
+	CreateFile(filename string, r io.Reader) error
+	GetFile(name string) (io.Reader, error)
+	MakeExecutable(filename string) error
+	SetMaxProcesses(i int) Runner
 	TimeLimit(duration time.Duration) Runner
 	MemoryLimit(limit int) Runner
+	AddressSpaceLimit(limit int64) Runner
+	CgroupRoot(root string) Runner
+	Stdin(reader io.Reader) Runner
+	Stdout(writer io.Writer) Runner
+	Stderr(writer io.Writer) Runner
+	Run(command []string, needStatus bool) (structs.Verdict, error)
+	Stats() RunStats
+	Cleanup() error
+}
+
+// NewRunner creates and initializes the runner selected in config, caller must call Cleanup on it
+func NewRunner(conf configs.SectionRunner) (Runner, error) {
+	var (
+		r   Runner
+		err error
+	)
+	switch conf.Type {
+	case "", "dummy":
+		r, err = NewDummy()
+	case "isolated":
+		r, err = NewIsolated()
+	default:
+		return nil, errors.New("unknown runner type: " + conf.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.CgroupRoot(conf.CgroupRoot)
+	return r, nil
+}
+
+// RunStats are the resource usages of the last process run in a sandbox
+type RunStats struct {
+	PeakMemory int64 // bytes
+	WallTime   time.Duration
+	CPUTime    time.Duration
+	ExitCode   int // -1 if the process was killed by a signal
 }
 
 type Dummy struct {
-	tmpdir string
-	env    []string
-	tl     time.Duration
+	tmpdir     string
+	env        []string
+	tl         time.Duration
+	ml         int64
+	asl        int64
+	maxProcs   int
+	cgroupRoot string
+	stats      RunStats
 
 	stdin          io.Reader
 	stdout, stderr io.Writer
@@ -52,11 +103,11 @@ func (s *Dummy) Init() error {
 }
 
 func (s *Dummy) Pwd() string {
-	return s.tmpdir
+	return s.workingDir
 }
 
 func (s *Dummy) CreateFile(filename string, r io.Reader) error {
-	f, err := os.Create(filename)
+	f, err := os.Create(filepath.Join(s.Pwd(), filename))
 	if err != nil {
 		pkg.Log.Debug("Error occurred while creating file ", err)
 		return err
@@ -81,12 +132,14 @@ func (s *Dummy) GetFile(name string) (io.Reader, error) {
 
 func (s *Dummy) MakeExecutable(filename string) error {
 
-	err := os.Chmod(filename, 0777)
+	err := os.Chmod(filepath.Join(s.Pwd(), filename), 0777)
 
 	return err
 }
 
+// SetMaxProcesses limits the number of processes, it is only enforced when a cgroup is available
 func (s *Dummy) SetMaxProcesses(i int) Runner {
+	s.maxProcs = i
 	return s
 }
 
@@ -95,10 +148,28 @@ func (s *Dummy) TimeLimit(tl time.Duration) Runner {
 	return s
 }
 
-func (s *Dummy) MemoryLimit(int) Runner {
+// MemoryLimit sets the memory limit in bytes, zero means unlimited
+func (s *Dummy) MemoryLimit(limit int) Runner {
+	s.ml = int64(limit)
 	return s
 }
 
+// AddressSpaceLimit sets the RLIMIT_AS used when there is no cgroup, zero means unlimited
+func (s *Dummy) AddressSpaceLimit(limit int64) Runner {
+	s.asl = limit
+	return s
+}
+
+// CgroupRoot sets a delegated cgroup v2 directory used for enforcing memory and process limits
+func (s *Dummy) CgroupRoot(root string) Runner {
+	s.cgroupRoot = root
+	return s
+}
+
+func (s *Dummy) Stats() RunStats {
+	return s.stats
+}
+
 func (s *Dummy) Stdin(reader io.Reader) Runner {
 	s.stdin = reader
 	return s
@@ -114,20 +185,34 @@ func (s *Dummy) Stdout(writer io.Writer) Runner {
 	return s
 }
 
-func (s *Dummy) Run(prg string, needStatus bool) (structs.Verdict, error) {
-	cmd := exec.Command("python3", prg)
+func (s *Dummy) Run(command []string, needStatus bool) (structs.Verdict, error) {
+	if len(command) == 0 {
+		return structs.VerdictUnknown, errors.New("empty command")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
 	cmd.Stdin = s.stdin
 	cmd.Stdout = s.stdout
 	cmd.Stderr = s.stderr
 	cmd.Dir = s.workingDir
 	//cmd.Env = append(s.env, "PATH="+os.Getenv("PATH")+":"+s.tmpdir)
 
+	return s.execute(cmd, s.asl)
+}
+
+// execute runs cmd while applying the time and resource limits of the sandbox
+func (s *Dummy) execute(cmd *exec.Cmd, addressSpace int64) (structs.Verdict, error) {
 	var (
 		errKill, errWait error
 		finish           = make(chan bool, 1)
 		wg               sync.WaitGroup
 	)
 
+	limiter := newResourceLimiter(s.ml, addressSpace, s.maxProcs, s.cgroupRoot)
+	defer limiter.cleanup()
+	limiter.prepare(cmd)
+
+	s.stats = RunStats{}
+	startedAt := time.Now()
 	start := time.NewTimer(s.tl)
 	if err := cmd.Start(); err != nil {
 		return structs.VerdictUnknown, err
@@ -153,6 +238,16 @@ func (s *Dummy) Run(prg string, needStatus bool) (structs.Verdict, error) {
 
 	wg.Wait()
 
+	s.stats.WallTime = time.Since(startedAt)
+	if cmd.ProcessState != nil {
+		s.stats.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		s.stats.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	s.stats.PeakMemory = limiter.peak(cmd.ProcessState)
+	if v != structs.VerdictUnknown && limiter.memoryExceeded(cmd.ProcessState) {
+		v = structs.VerdictMemoryLimit
+	}
+
 	//if errWait != nil && (strings.HasPrefix(errWait.Error(), "exit status") || strings.HasPrefix(errWait.Error(), "signal:")) {
 	if _, ok := errWait.(*exec.ExitError); ok {
 		if v == structs.VerdictOK {
@@ -172,35 +267,60 @@ func (s *Dummy) Cleanup() error {
 	return os.RemoveAll(s.tmpdir)
 }
 
-func RunTask(timeLimit time.Duration, memoryLimit int, code string, input io.Reader, output io.Writer, stderr io.Writer) (structs.Verdict, error) {
-	// runner for python
-	d, err := NewDummy()
+// Compile builds the submission source which must already be in the sandbox.
+// it returns VerdictCompileError and the compiler output if the build fails.
+func Compile(d Runner, lang Language) (structs.Verdict, string, error) {
+	if !lang.NeedsCompile() {
+		return structs.VerdictOK, "", nil
+	}
+
+	var output bytes.Buffer
+	d.MemoryLimit(0)
+	d.AddressSpaceLimit(0)
+	d.SetMaxProcesses(CompileMaxProcesses)
+	d.TimeLimit(CompileTimeLimit)
+	d.Stdin(strings.NewReader(""))
+	d.Stdout(&output)
+	d.Stderr(&output)
+
+	v, err := d.Run(lang.CompileCmd, false)
 	if err != nil {
-		return structs.VerdictUnknown, err
+		return structs.VerdictUnknown, output.String(), err
+	}
+	switch v {
+	case structs.VerdictOK:
+		return structs.VerdictOK, output.String(), nil
+	case structs.VerdictTimeLimit:
+		output.WriteString("\ncompilation time limit exceeded")
 	}
+	return structs.VerdictCompileError, output.String(), nil
+}
 
+// RunTask runs an already compiled submission on a single input
+func RunTask(d Runner, lang Language, timeLimit time.Duration, memoryLimit int, input io.Reader, output io.Writer, stderr io.Writer) (structs.Verdict, RunStats, error) {
 	d.MemoryLimit(memoryLimit)
+	d.AddressSpaceLimit(lang.AddressSpaceLimit(memoryLimit))
+	d.SetMaxProcesses(1)
 	d.TimeLimit(timeLimit)
 	d.Stdin(input)
 	d.Stdout(output)
 	d.Stderr(stderr)
-	const fileName = "runner_test.py"
-	err = d.CreateFile(fileName, strings.NewReader(code))
-	if err != nil {
-		return structs.VerdictUnknown, err
-	}
-	err = d.MakeExecutable(fileName)
-	if err != nil {
-		return structs.VerdictUnknown, err
-	}
 
-	v, err := d.Run("runner_test.py", false)
+	v, err := d.Run(lang.RunCmd, false)
+	return v, d.Stats(), err
+}
+
+// Prepare creates a new sandbox containing the submission source, caller must call Cleanup on it
+func Prepare(lang Language, code string, conf configs.SectionRunner) (Runner, error) {
+	d, err := NewRunner(conf)
 	if err != nil {
-		return v, err
+		return nil, err
 	}
-	err = d.Cleanup()
+
+	err = d.CreateFile(lang.SourceFile, strings.NewReader(code))
 	if err != nil {
-		pkg.Log.Warning("error on doing cleanup of runner: ", err)
+		d.Cleanup()
+		return nil, err
 	}
-	return v, nil
+	return d, nil
 }
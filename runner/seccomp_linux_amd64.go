@@ -0,0 +1,7 @@
+package runner
+
+import "golang.org/x/sys/unix"
+
+const seccompAuditArch = unix.AUDIT_ARCH_X86_64
+
+var seccompForkSyscalls = []uint32{unix.SYS_FORK, unix.SYS_VFORK}
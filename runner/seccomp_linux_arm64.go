@@ -0,0 +1,8 @@
+package runner
+
+import "golang.org/x/sys/unix"
+
+const seccompAuditArch = unix.AUDIT_ARCH_AARCH64
+
+// arm64 has no fork and vfork syscalls, everything goes through clone
+var seccompForkSyscalls = []uint32{}